@@ -0,0 +1,245 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAPIDepth  = 50
+	defaultAPITrades = 100
+	maxAPIDepth      = 1000
+)
+
+type API struct {
+	Book   *OrderBook
+	Trades *Queue
+}
+
+type apiLevel struct {
+	Price  decimal.Decimal `json:"price"`
+	Size   decimal.Decimal `json:"size"`
+	Orders int             `json:"orders"`
+}
+
+type apiBook struct {
+	Product  string           `json:"product"`
+	Sequence int64            `json:"sequence"`
+	Time     time.Time        `json:"time"`
+	Group    *decimal.Decimal `json:"group,omitempty"`
+	Bids     []apiLevel       `json:"bids"`
+	Asks     []apiLevel       `json:"asks"`
+}
+
+type apiTrade struct {
+	TradeId int64           `json:"trade_id"`
+	Side    Side            `json:"side"`
+	Price   decimal.Decimal `json:"price"`
+	Size    decimal.Decimal `json:"size"`
+	Time    time.Time       `json:"time"`
+}
+
+type apiLatency struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
+
+type apiStats struct {
+	Product      string                `json:"product"`
+	Sequence     int64                 `json:"sequence"`
+	Messages     int64                 `json:"messages"`
+	ByType       map[MessageType]int64 `json:"by_type"`
+	Rate         float64               `json:"rate"`
+	Gaps         int64                 `json:"gaps"`
+	Syncs        int64                 `json:"syncs"`
+	Resyncs      int64                 `json:"resyncs"`
+	Reconnects   int64                 `json:"reconnects"`
+	Errors       int64                 `json:"errors"`
+	FeedLatency  apiLatency            `json:"feed_latency"`
+	ApplyLatency apiLatency            `json:"apply_latency"`
+	TotalLatency apiLatency            `json:"total_latency"`
+}
+
+func (a API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	switch r.URL.Path {
+	case "/book":
+		a.book(w, r)
+	case "/trades":
+		a.trades(w, r)
+	case "/stats":
+		a.stats(w)
+	default:
+		apiError(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+func (a API) book(w http.ResponseWriter, r *http.Request) {
+	depth, err := intParam(r, "depth", defaultAPIDepth, maxAPIDepth)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var group decimal.Decimal
+	if v := r.URL.Query().Get("group"); v != "" {
+		group, err = decimal.NewFromString(v)
+		if err != nil || !group.IsPositive() {
+			apiError(w, http.StatusBadRequest, fmt.Sprintf("Invalid group: %v", v))
+			return
+		}
+	}
+
+	count := depth
+	if group.IsPositive() {
+		count = math.MaxInt32
+	}
+
+	snap := a.Book.Snapshot(count)
+
+	resp := apiBook{
+		Product:  snap.Product,
+		Sequence: snap.Sequence,
+		Time:     snap.Time,
+		Bids:     groupLevels(snap.Bids, Buy, group, depth),
+		Asks:     groupLevels(snap.Asks, Sell, group, depth),
+	}
+
+	if group.IsPositive() {
+		resp.Group = &group
+	}
+
+	writeAPI(w, resp)
+}
+
+func (a API) trades(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", defaultAPITrades, a.Trades.Length())
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	trades := a.Trades.Last(limit)
+
+	resp := make([]apiTrade, 0, len(trades))
+	for _, e := range trades {
+		t := e.(trade)
+
+		resp = append(resp, apiTrade{t.TradeId, t.Side, t.Price, t.Size, t.Time})
+	}
+
+	writeAPI(w, resp)
+}
+
+func (a API) stats(w http.ResponseWriter) {
+	st := a.Book.Stats()
+
+	writeAPI(w, apiStats{
+		Product:      a.Book.Product(),
+		Sequence:     a.Book.Sequence(),
+		Messages:     st.Messages,
+		ByType:       st.ByType,
+		Rate:         st.Rate,
+		Gaps:         st.Gaps,
+		Syncs:        st.Syncs,
+		Resyncs:      st.Resyncs,
+		Reconnects:   st.Reconnects,
+		Errors:       st.Errors,
+		FeedLatency:  latency(st.FeedLatency),
+		ApplyLatency: latency(st.ApplyLatency),
+		TotalLatency: latency(st.TotalLatency),
+	})
+}
+
+func groupLevels(levels []Level, side Side, group decimal.Decimal, depth int) []apiLevel {
+	size := len(levels)
+	if size > depth {
+		size = depth
+	}
+
+	grouped := make([]apiLevel, 0, size)
+
+	for _, l := range levels {
+		price := l.Price
+
+		if group.IsPositive() {
+			if side == Buy {
+				price = price.Div(group).Floor().Mul(group)
+			} else {
+				price = price.Div(group).Ceil().Mul(group)
+			}
+		}
+
+		n := len(grouped)
+		if n > 0 && grouped[n-1].Price.Equal(price) {
+			grouped[n-1].Size = grouped[n-1].Size.Add(l.Size)
+			grouped[n-1].Orders += l.Count
+			continue
+		}
+
+		if n == depth {
+			break
+		}
+
+		grouped = append(grouped, apiLevel{price, l.Size, l.Count})
+	}
+
+	return grouped
+}
+
+func latency(s HistogramSummary) apiLatency {
+	return apiLatency{
+		Count: s.Count,
+		Mean:  s.Mean.Seconds(),
+		Min:   s.Min.Seconds(),
+		Max:   s.Max.Seconds(),
+		P50:   s.P50.Seconds(),
+		P90:   s.P90.Seconds(),
+		P99:   s.P99.Seconds(),
+	}
+}
+
+func intParam(r *http.Request, name string, def, max int) (int, error) {
+	n := def
+
+	if v := r.URL.Query().Get(name); v != "" {
+		var err error
+
+		n, err = strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("Invalid %v: %v", name, v)
+		}
+	}
+
+	if n > max {
+		n = max
+	}
+
+	return n, nil
+}
+
+func writeAPI(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
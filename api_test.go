@@ -0,0 +1,159 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func apiServer() *httptest.Server {
+	trades := NewQueue()
+	for i := int64(1); i <= 3; i++ {
+		trades.Push(trade{Message: Message{Type: TypeMatch, TradeId: i, Side: Buy,
+			Price: decimal.NewFromInt(100 + i), Size: decimal.NewFromInt(1),
+			Time: time.Unix(1600000000+i, 0)}})
+	}
+
+	return httptest.NewServer(API{Book: depthBook(), Trades: trades})
+}
+
+func getAPI(t *testing.T, srv *httptest.Server, path string, v interface{}) int {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestAPIBook(t *testing.T) {
+	srv := apiServer()
+	defer srv.Close()
+
+	tests := []struct {
+		query string
+		bids  []string
+		asks  []string
+	}{
+		{"", []string{"99", "98"}, []string{"101", "102"}},
+		{"?depth=1", []string{"99"}, []string{"101"}},
+		{"?depth=9223372036854775807", []string{"99", "98"}, []string{"101", "102"}},
+		{"?depth=9223372036854775807&group=5", []string{"95"}, []string{"105"}},
+		{"?group=2", []string{"98"}, []string{"102"}},
+	}
+
+	for _, tt := range tests {
+		var book apiBook
+		if status := getAPI(t, srv, "/book"+tt.query, &book); status != http.StatusOK {
+			t.Errorf("%v: expected 200, got %v", tt.query, status)
+			continue
+		}
+
+		for _, side := range []struct {
+			want []string
+			got  []apiLevel
+		}{{tt.bids, book.Bids}, {tt.asks, book.Asks}} {
+			if len(side.got) != len(side.want) {
+				t.Errorf("%v: expected %v levels, got %v", tt.query, len(side.want), len(side.got))
+				continue
+			}
+
+			for i, price := range side.want {
+				if side.got[i].Price.String() != price {
+					t.Errorf("%v: expected level %v at %v, got %v", tt.query, i, price,
+						side.got[i].Price)
+				}
+			}
+		}
+	}
+
+	var book apiBook
+	getAPI(t, srv, "/book?group=2", &book)
+	if !book.Bids[0].Size.Equal(decimal.NewFromInt(3)) || book.Bids[0].Orders != 2 {
+		t.Errorf("Expected grouped bid of 3 across 2 orders, got %+v", book.Bids[0])
+	}
+
+	for _, query := range []string{"?depth=0", "?depth=-1", "?depth=x", "?group=0"} {
+		if status := getAPI(t, srv, "/book"+query, nil); status != http.StatusBadRequest {
+			t.Errorf("%v: expected 400, got %v", query, status)
+		}
+	}
+}
+
+func TestAPITrades(t *testing.T) {
+	srv := apiServer()
+	defer srv.Close()
+
+	tests := []struct {
+		query string
+		ids   []int64
+	}{
+		{"", []int64{3, 2, 1}},
+		{"?limit=2", []int64{3, 2}},
+		{"?limit=9223372036854775807", []int64{3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		var trades []apiTrade
+		if status := getAPI(t, srv, "/trades"+tt.query, &trades); status != http.StatusOK {
+			t.Errorf("%v: expected 200, got %v", tt.query, status)
+			continue
+		}
+
+		if len(trades) != len(tt.ids) {
+			t.Errorf("%v: expected %v trades, got %v", tt.query, len(tt.ids), len(trades))
+			continue
+		}
+
+		for i, id := range tt.ids {
+			if trades[i].TradeId != id {
+				t.Errorf("%v: expected trade %v at %v, got %v", tt.query, id, i, trades[i].TradeId)
+			}
+		}
+	}
+
+	if status := getAPI(t, srv, "/trades?limit=0", nil); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a zero limit, got %v", status)
+	}
+}
+
+func TestAPIStats(t *testing.T) {
+	srv := apiServer()
+	defer srv.Close()
+
+	var stats apiStats
+	if status := getAPI(t, srv, "/stats", &stats); status != http.StatusOK {
+		t.Fatalf("Expected 200, got %v", status)
+	}
+
+	if stats.Product != "ETH-USD" || stats.Sequence != 1 {
+		t.Errorf("Expected ETH-USD at sequence 1, got %v at %v", stats.Product, stats.Sequence)
+	}
+
+	if status := getAPI(t, srv, "/missing", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %v", status)
+	}
+
+	resp, err := http.Post(srv.URL+"/stats", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %v", resp.StatusCode)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+const (
+	verifyMethod = "GET"
+	verifyPath   = "/users/self/verify"
+)
+
+type Credentials struct {
+	Key        string
+	Secret     string
+	Passphrase string
+}
+
+func (c Credentials) Valid() bool {
+	return c.Key != "" && c.Secret != "" && c.Passphrase != ""
+}
+
+func Sign(secret, timestamp, method, path, body string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp + method + path + body))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (c Credentials) sign(sub *Sub, now time.Time) error {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	signature, err := Sign(c.Secret, timestamp, verifyMethod, verifyPath, "")
+	if err != nil {
+		return err
+	}
+
+	sub.Signature = signature
+	sub.Key = c.Key
+	sub.Passphrase = c.Passphrase
+	sub.Timestamp = timestamp
+
+	return nil
+}
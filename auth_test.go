@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "c3BlY3RhdG9yLXRlc3Qtc2VjcmV0LTAxMjM0NTY3ODk="
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		want               string
+	}{
+		{"GET", "/users/self/verify", "", "mlBl4tK0dTCFFIfTrKO5fztXdXDWvBxdSqdSXevmDUQ="},
+		{"POST", "/orders", `{"size":"1.0"}`, "ag9T/bl5myj07sPxQGE2U6oneKefgqOGG7sxEL2CGj0="},
+	}
+
+	for _, tt := range tests {
+		got, err := Sign(testSecret, "1600000000", tt.method, tt.path, tt.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Sign(%v %v) = %v, expected %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := Sign("not base64!", "1600000000", "GET", "/", ""); err == nil {
+		t.Error("Expected an error for an invalid secret")
+	}
+}
+
+func TestSubscribeSigned(t *testing.T) {
+	subs := make(chan Sub, 1)
+
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !websocket.IsWebSocketUpgrade(r) {
+			http.NotFound(w, r)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var sub Sub
+		if conn.ReadJSON(&sub) == nil {
+			subs <- sub
+		}
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	creds := Credentials{Key: "key", Secret: testSecret, Passphrase: "passphrase"}
+
+	m, err := NewBookManager(context.Background(), []string{"ETH-USD"}, Options{
+		WebsocketURL: "ws" + strings.TrimPrefix(srv.URL, "http"),
+		RestURL:      srv.URL,
+		Credentials:  creds,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var sub Sub
+	select {
+	case sub = <-subs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the subscribe frame")
+	}
+
+	if sub.Key != creds.Key || sub.Passphrase != creds.Passphrase {
+		t.Errorf("Expected key and passphrase, got %q %q", sub.Key, sub.Passphrase)
+	}
+
+	if sub.Timestamp == "" {
+		t.Fatal("Expected a timestamp")
+	}
+
+	want, _ := Sign(testSecret, sub.Timestamp, "GET", "/users/self/verify", "")
+	if sub.Signature != want {
+		t.Errorf("Expected signature %v, got %v", want, sub.Signature)
+	}
+
+	var user bool
+	for _, c := range sub.Channels {
+		user = user || c == "user"
+	}
+
+	if !user {
+		t.Errorf("Expected the user channel, got %v", sub.Channels)
+	}
+}
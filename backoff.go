@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	DefaultReconnectMin = 500 * time.Millisecond
+	DefaultReconnectMax = 30 * time.Second
+	DefaultStaleTimeout = 5 * time.Second
+
+	DefaultSnapshotInterval = 400 * time.Millisecond
+	DefaultRequestTimeout   = 10 * time.Second
+)
+
+type backoff struct {
+	min, max time.Duration
+	attempt  int
+}
+
+func (b *backoff) Next() time.Duration {
+	d := b.max
+	if b.attempt < 32 && b.min<<uint(b.attempt) < b.max {
+		d = b.min << uint(b.attempt)
+	}
+
+	b.attempt++
+
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type BookManager struct {
+	Err   <-chan error
+	State <-chan ConnState
+
+	products []string
+	books    map[string]*OrderBook
+
+	err   chan error
+	state chan ConnState
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	termLock sync.Mutex
+	termErr  error
+
+	mode Mode
+	user bool
+
+	validateInterval time.Duration
+	resyncInvalid    bool
+	staleTimeout     time.Duration
+	snapshotInterval time.Duration
+
+	checkpoints CheckpointStore
+	log         MessageLog
+	interval    time.Duration
+
+	feed    Feed
+	backoff backoff
+
+	countLock  sync.Mutex
+	reconnects int64
+	errors     int64
+}
+
+func NewBookManager(ctx context.Context, products []string,
+	opts Options) (*BookManager, error) {
+	var m BookManager
+
+	opts = opts.withDefaults()
+
+	m.feed = opts.Feed
+	if m.feed == nil {
+		m.feed = NewCoinbaseFeed(opts)
+	}
+
+	err := m.feed.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	m.backoff = backoff{min: opts.ReconnectMin, max: opts.ReconnectMax}
+	m.mode = opts.Mode
+	m.user = opts.Credentials.Valid()
+	m.validateInterval = opts.ValidateInterval
+	m.resyncInvalid = opts.ResyncInvalid
+	m.staleTimeout = opts.StaleTimeout
+	m.snapshotInterval = opts.SnapshotInterval
+	m.checkpoints = opts.Checkpoints
+	m.log = opts.MessageLog
+	m.interval = opts.CheckpointInterval
+
+	m.err = make(chan error, 0)
+	m.Err = m.err
+	m.state = make(chan ConnState, 16)
+	m.State = m.state
+
+	m.books = make(map[string]*OrderBook)
+	for _, p := range products {
+		if _, ok := m.books[p]; ok {
+			continue
+		}
+
+		m.products = append(m.products, p)
+		m.books[p] = newOrderBook(p, &m)
+		m.books[p].retry = backoff{min: opts.ReconnectMin, max: opts.ReconnectMax}
+	}
+
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	m.watchBooks(ctx)
+
+	return &m, nil
+}
+
+func (m *BookManager) Book(product string) *OrderBook {
+	return m.books[product]
+}
+
+func (m *BookManager) Products() []string {
+	return append([]string{}, m.products...)
+}
+
+func (m *BookManager) Close() error {
+	m.cancel()
+
+	return m.Wait()
+}
+
+func (m *BookManager) Wait() error {
+	<-m.done
+
+	m.termLock.Lock()
+	defer m.termLock.Unlock()
+
+	return m.termErr
+}
+
+func (m *BookManager) watchBooks(parent context.Context) {
+	go func() {
+		<-m.ctx.Done()
+		m.feed.Close()
+	}()
+
+	go func() {
+		defer func() {
+			m.termLock.Lock()
+			m.termErr = parent.Err()
+			m.termLock.Unlock()
+
+			close(m.err)
+			for _, b := range m.books {
+				b.closeSubscriptions()
+			}
+			close(m.state)
+			m.feed.Close()
+
+			close(m.done)
+		}()
+
+		connected := true
+
+		for m.isRunning() {
+			if !connected {
+				m.setState(StateConnecting)
+
+				err := m.feed.Connect()
+				if err != nil {
+					m.sendError(err)
+					m.setState(StateDown)
+
+					if !m.wait(m.backoff.Next()) {
+						return
+					}
+
+					continue
+				}
+
+				if !m.isRunning() {
+					return
+				}
+
+				m.countLock.Lock()
+				m.reconnects++
+				m.countLock.Unlock()
+			}
+
+			err := m.session()
+			connected = false
+
+			if !m.isRunning() {
+				return
+			}
+
+			if err != nil && err != io.EOF {
+				m.sendError(err)
+			}
+
+			m.feed.Close()
+			m.setState(StateDown)
+
+			if !m.wait(m.backoff.Next()) {
+				return
+			}
+		}
+	}()
+}
+
+func (m *BookManager) session() error {
+	channel := "full"
+	if m.mode == ModeLevel2 {
+		channel = "level2"
+	}
+
+	channels := []string{channel, "heartbeat"}
+	if m.user {
+		channels = append(channels, "user")
+	}
+
+	err := m.feed.Subscribe(m.products, channels)
+	if err != nil {
+		return err
+	}
+
+	msgs := make(chan Message, 1024)
+	errs := make(chan error, 1)
+	quit := make(chan struct{})
+	reading := make(chan struct{})
+
+	go m.readFeed(msgs, errs, quit, reading)
+	defer func() {
+		close(quit)
+		m.feed.Close()
+		<-reading
+	}()
+
+	snaps := newSnapshotQueue(m.feed, m.snapshotInterval, len(m.books))
+	defer snaps.Close()
+
+	var validate <-chan time.Time
+	if m.validateInterval > 0 && m.mode == ModeFull {
+		ticker := time.NewTicker(m.validateInterval)
+		defer ticker.Stop()
+
+		validate = ticker.C
+	}
+
+	var watchdog <-chan time.Time
+	if m.staleTimeout > 0 {
+		ticker := time.NewTicker(m.staleTimeout / 2)
+		defer ticker.Stop()
+
+		watchdog = ticker.C
+	}
+
+	last := time.Now()
+
+	m.setState(StateResyncing)
+	for _, b := range m.books {
+		b.check = nil
+		b.validating = false
+		b.retry.Reset()
+		b.stats.sync()
+
+		if m.mode == ModeLevel2 {
+			b.syncing = true
+		} else {
+			b.sync(snaps)
+		}
+	}
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return m.ctx.Err()
+		case err := <-errs:
+			return err
+		case res := <-snaps.Synced:
+			b := m.books[res.product]
+
+			if res.err != nil {
+				m.sendError(res.err)
+				snaps.Sync(b.coin, b.retry.Next())
+				continue
+			}
+
+			b.retry.Reset()
+
+			if !b.loadOrderBook(res.snap) {
+				b.resync(snaps)
+				continue
+			}
+
+			m.synced()
+		case <-validate:
+			for _, b := range m.books {
+				b.requestCheck(snaps)
+			}
+		case res := <-snaps.Checked:
+			b := m.books[res.product]
+			b.validating = false
+
+			if res.err != nil {
+				m.sendError(res.err)
+				continue
+			}
+
+			b.validate(res.snap, snaps)
+		case <-watchdog:
+			if time.Since(last) > m.staleTimeout {
+				m.setState(StateStale)
+				return errStale
+			}
+		case msg := <-msgs:
+			last = time.Now()
+
+			if msg.Type == TypeHeartbeat {
+				continue
+			}
+
+			if msg.Type == TypeError {
+				m.sendError(fmt.Errorf("Feed error: %v", msg.Message))
+				continue
+			}
+
+			b, ok := m.books[msg.ProductId]
+			if !ok {
+				continue
+			}
+
+			if msg.UserId != "" {
+				b.handleOwn(msg)
+
+				if m.mode == ModeLevel2 {
+					continue
+				}
+			}
+
+			b.handle(msg, snaps)
+		}
+	}
+}
+
+func (m *BookManager) readFeed(msgs chan<- Message, errs chan<- error,
+	quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		msg, err := m.feed.Read()
+		if msg.Received.IsZero() {
+			msg.Received = time.Now()
+		}
+		if _, ok := err.(*DecodeError); ok {
+			m.sendError(err)
+
+			select {
+			case <-quit:
+				return
+			default:
+			}
+
+			continue
+		}
+		if err != nil {
+			errs <- err
+			return
+		}
+
+		select {
+		case msgs <- msg:
+		case <-quit:
+			return
+		}
+	}
+}
+
+func (m *BookManager) synced() {
+	for _, b := range m.books {
+		if b.syncing {
+			return
+		}
+	}
+
+	m.backoff.Reset()
+	m.setState(StateLive)
+}
+
+func (m *BookManager) counts() (int64, int64) {
+	m.countLock.Lock()
+	defer m.countLock.Unlock()
+
+	return m.reconnects, m.errors
+}
+
+func (m *BookManager) isRunning() bool {
+	return m.ctx.Err() == nil
+}
+
+func (m *BookManager) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-m.ctx.Done():
+		return false
+	}
+}
+
+func (m *BookManager) setState(s ConnState) {
+	select {
+	case m.state <- s:
+	default:
+	}
+}
+
+func (m *BookManager) sendError(err error) {
+	m.countLock.Lock()
+	m.errors++
+	m.countLock.Unlock()
+
+	select {
+	case m.err <- err:
+	default:
+	}
+}
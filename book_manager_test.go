@@ -0,0 +1,260 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testFeed struct {
+	lock      sync.Mutex
+	connects  int
+	snapshots []time.Time
+	snapshot  func(product string, n int) (FeedSnapshot, error)
+	calls     map[string]int
+
+	msgs    chan Message
+	closed  chan struct{}
+	done    bool
+	garbage bool
+}
+
+func newTestFeed(snapshot func(product string, n int) (FeedSnapshot, error)) *testFeed {
+	return &testFeed{
+		snapshot: snapshot,
+		calls:    make(map[string]int),
+		msgs:     make(chan Message, 1024),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (f *testFeed) Connect() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.connects++
+
+	if f.done {
+		f.closed = make(chan struct{})
+		f.done = false
+	}
+
+	return nil
+}
+
+func (f *testFeed) Subscribe(products []string, channels []string) error {
+	return nil
+}
+
+func (f *testFeed) Snapshot(product string) (FeedSnapshot, error) {
+	f.lock.Lock()
+	f.snapshots = append(f.snapshots, time.Now())
+	n := f.calls[product]
+	f.calls[product]++
+	f.lock.Unlock()
+
+	return f.snapshot(product, n)
+}
+
+func (f *testFeed) Read() (Message, error) {
+	f.lock.Lock()
+	closed, garbage := f.closed, f.garbage
+	f.lock.Unlock()
+
+	if garbage {
+		select {
+		case <-closed:
+			return Message{}, io.EOF
+		default:
+			return Message{}, &DecodeError{errors.New("invalid"), []byte("{")}
+		}
+	}
+
+	select {
+	case msg := <-f.msgs:
+		return msg, nil
+	case <-closed:
+		return Message{}, io.EOF
+	}
+}
+
+func (f *testFeed) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.done {
+		f.done = true
+		close(f.closed)
+	}
+
+	return nil
+}
+
+func (f *testFeed) stats() (int, []time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.connects, append([]time.Time{}, f.snapshots...)
+}
+
+func waitState(t *testing.T, states <-chan ConnState, want ConnState) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		select {
+		case s := <-states:
+			if s == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for state %v", want)
+		}
+	}
+}
+
+func TestBookManagerRetriesFailedSnapshot(t *testing.T) {
+	interval := 20 * time.Millisecond
+
+	f := newTestFeed(func(product string, n int) (FeedSnapshot, error) {
+		if product == "BTC-USD" && n == 0 {
+			return FeedSnapshot{}, errors.New("429 Too Many Requests")
+		}
+
+		return FeedSnapshot{Sequence: 10, Bids: []Entry{{
+			Id:    "a",
+			Side:  Buy,
+			Price: decimal.NewFromInt(100),
+			Size:  decimal.NewFromInt(1),
+		}}}, nil
+	})
+
+	m, err := NewBookManager(context.Background(), []string{"ETH-USD", "BTC-USD"},
+		Options{
+			Feed:             f,
+			ReconnectMin:     10 * time.Millisecond,
+			ReconnectMax:     20 * time.Millisecond,
+			SnapshotInterval: interval,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	waitState(t, m.State, StateLive)
+
+	connects, snapshots := f.stats()
+	if connects != 1 {
+		t.Errorf("Expected a single connection, got %v", connects)
+	}
+
+	if len(snapshots) != 3 {
+		t.Fatalf("Expected 3 snapshot requests, got %v", len(snapshots))
+	}
+
+	for i := 1; i < len(snapshots); i++ {
+		if d := snapshots[i].Sub(snapshots[i-1]); d < interval {
+			t.Errorf("Snapshot %v requested %v after the previous one", i, d)
+		}
+	}
+
+	for _, p := range m.Products() {
+		if seq := m.Book(p).Sequence(); seq != 10 {
+			t.Errorf("Expected %v at sequence 10, got %v", p, seq)
+		}
+
+		if st := m.Book(p).Stats(); st.Syncs != 1 || st.Resyncs != 0 {
+			t.Errorf("Expected 1 sync and no resyncs for %v, got %v and %v", p,
+				st.Syncs, st.Resyncs)
+		}
+	}
+
+	if _, errs := m.counts(); errs != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+}
+
+func TestBookManagerCloseJoinsReader(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		f := newTestFeed(func(product string, n int) (FeedSnapshot, error) {
+			return FeedSnapshot{Sequence: 1}, nil
+		})
+		f.garbage = true
+
+		m, err := NewBookManager(context.Background(), []string{"ETH-USD"},
+			Options{Feed: f})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(time.Millisecond)
+
+		if err := m.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		for range m.Err {
+		}
+	}
+}
+
+func TestBookManagerAppliesOwnMessages(t *testing.T) {
+	release := make(chan struct{})
+
+	f := newTestFeed(func(product string, n int) (FeedSnapshot, error) {
+		<-release
+		return FeedSnapshot{Sequence: 10}, nil
+	})
+
+	open := Message{Type: TypeOpen, ProductId: "ETH-USD", Sequence: 11, Side: Buy,
+		OrderId: "mine", Price: decimal.NewFromInt(100),
+		RemainingSize: decimal.NewFromInt(2), UserId: "user"}
+	match := Message{Type: TypeMatch, ProductId: "ETH-USD", Sequence: 12, Side: Buy,
+		MakerOrderId: "mine", TakerOrderId: "other", TradeId: 7,
+		Price: decimal.NewFromInt(100), Size: decimal.NewFromInt(1), UserId: "user"}
+
+	for _, msg := range []Message{open, open, match, match} {
+		f.msgs <- msg
+	}
+
+	m, err := NewBookManager(context.Background(), []string{"ETH-USD"},
+		Options{Feed: f})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	b := m.Book("ETH-USD")
+	sub := b.Subscribe(16, PolicyBlock)
+	close(release)
+
+	for seq := int64(11); seq <= 12; seq++ {
+		select {
+		case msg := <-sub.C:
+			if msg.Sequence != seq {
+				t.Fatalf("Expected sequence %v, got %v", seq, msg.Sequence)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for sequence %v", seq)
+		}
+	}
+
+	st, ok := b.Order("mine")
+	if !ok || !st.Own || !st.Size.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("Expected own order with size 1, got %+v %v", st, ok)
+	}
+
+	if fills := b.Fills(0); len(fills) != 1 {
+		t.Errorf("Expected 1 fill, got %v", len(fills))
+	}
+
+	if stats := b.Stats(); stats.Gaps != 0 {
+		t.Errorf("Expected no gaps, got %v", stats.Gaps)
+	}
+}
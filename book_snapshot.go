@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"time"
+)
+
+type BookSnapshot struct {
+	Product  string
+	Sequence int64
+	Time     time.Time
+	Bids     []Level
+	Asks     []Level
+}
+
+func (o *OrderBook) Snapshot(depth int) BookSnapshot {
+	var s BookSnapshot
+
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	s.Product = o.coin
+	s.Sequence, s.Time = o.position()
+	s.Bids = o.levels(Buy, depth)
+	s.Asks = o.levels(Sell, depth)
+
+	return s
+}
+
+func (s BookSnapshot) BestBid() (Level, bool) {
+	if len(s.Bids) == 0 {
+		return Level{}, false
+	}
+
+	return s.Bids[0], true
+}
+
+func (s BookSnapshot) BestAsk() (Level, bool) {
+	if len(s.Asks) == 0 {
+		return Level{}, false
+	}
+
+	return s.Asks[0], true
+}
+
+func (s BookSnapshot) Spread() decimal.Decimal {
+	bid, okBid := s.BestBid()
+	ask, okAsk := s.BestAsk()
+
+	if !okBid || !okAsk {
+		return decimal.Zero
+	}
+
+	return ask.Price.Sub(bid.Price)
+}
+
+func (s BookSnapshot) Mid() decimal.Decimal {
+	bid, okBid := s.BestBid()
+	ask, okAsk := s.BestAsk()
+
+	if !okBid || !okAsk {
+		return decimal.Zero
+	}
+
+	return bid.Price.Add(ask.Price).Div(decimal.New(2, 0))
+}
+
+func (s BookSnapshot) Imbalance() decimal.Decimal {
+	var bids, asks decimal.Decimal
+
+	for _, l := range s.Bids {
+		bids = bids.Add(l.Size)
+	}
+
+	for _, l := range s.Asks {
+		asks = asks.Add(l.Size)
+	}
+
+	total := bids.Add(asks)
+	if total.IsZero() {
+		return decimal.Zero
+	}
+
+	return bids.Sub(asks).Div(total)
+}
+
+func (s BookSnapshot) Crossed() bool {
+	bid, okBid := s.BestBid()
+	ask, okAsk := s.BestAsk()
+
+	return okBid && okAsk && !bid.Price.LessThan(ask.Price)
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCheckpointRetention = 15 * time.Minute
+	DefaultCheckpointLimit     = 32
+	DefaultMessageLogLimit     = 1 << 17
+)
+
+var errNoCheckpoint = errors.New("No checkpoint before target")
+var errNoHistory = errors.New("Checkpoints are not enabled")
+
+type Checkpoint struct {
+	Product  string            `json:"product"`
+	Sequence int64             `json:"sequence"`
+	Time     time.Time         `json:"time"`
+	Bids     []CheckpointLevel `json:"bids"`
+	Asks     []CheckpointLevel `json:"asks"`
+}
+
+type CheckpointLevel struct {
+	Price  decimal.Decimal   `json:"price"`
+	Orders []CheckpointOrder `json:"orders"`
+}
+
+type CheckpointOrder struct {
+	Id   string          `json:"id"`
+	Size decimal.Decimal `json:"size"`
+}
+
+type CheckpointStore interface {
+	Save(c Checkpoint) error
+	AtSequence(product string, seq int64) (Checkpoint, bool)
+	AtTime(product string, t time.Time) (Checkpoint, bool)
+}
+
+type MessageLog interface {
+	Append(msg Message)
+	Since(product string, seq int64, limit int) []Message
+}
+
+type Reconstructor struct {
+	Checkpoints CheckpointStore
+	Log         MessageLog
+}
+
+func (c Checkpoint) Snapshot() FeedSnapshot {
+	var snap FeedSnapshot
+
+	snap.Sequence = c.Sequence
+	snap.Bids = checkpointEntries(c.Bids, Buy)
+	snap.Asks = checkpointEntries(c.Asks, Sell)
+
+	return snap
+}
+
+func checkpointEntries(levels []CheckpointLevel, side Side) []Entry {
+	var n int
+	for _, l := range levels {
+		n += len(l.Orders)
+	}
+
+	entries := make([]Entry, 0, n)
+	for _, l := range levels {
+		for _, e := range l.Orders {
+			entries = append(entries, Entry{Id: e.Id, Side: side, Price: l.Price,
+				Size: e.Size})
+		}
+	}
+
+	return entries
+}
+
+func (o *OrderBook) Checkpoint() Checkpoint {
+	var c Checkpoint
+
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	c.Product = o.coin
+	c.Sequence, c.Time = o.position()
+	c.Bids = o.checkpointSide(Buy)
+	c.Asks = o.checkpointSide(Sell)
+
+	return c
+}
+
+func (o *OrderBook) checkpointSide(side Side) []CheckpointLevel {
+	index := o.index(side)
+
+	levels := make([]CheckpointLevel, 0, index.Size())
+
+	it := index.Iterator()
+	for it.Next() {
+		l := it.Value()
+
+		orders := make([]CheckpointOrder, 0, l.orders.Len())
+		for el := l.orders.Front(); el != nil; el = el.Next() {
+			e := el.Value.(Entry)
+			orders = append(orders, CheckpointOrder{e.Id, e.Size})
+		}
+
+		levels = append(levels, CheckpointLevel{l.price, orders})
+	}
+
+	return levels
+}
+
+func (r Reconstructor) AtSequence(product string, seq int64) (*OrderBook, error) {
+	if r.Checkpoints == nil || r.Log == nil {
+		return nil, errNoHistory
+	}
+
+	c, ok := r.Checkpoints.AtSequence(product, seq)
+	if !ok {
+		return nil, errNoCheckpoint
+	}
+
+	return r.replay(c, func(msg Message) bool {
+		return msg.Sequence <= seq
+	})
+}
+
+func (r Reconstructor) AtTime(product string, t time.Time) (*OrderBook, error) {
+	if r.Checkpoints == nil || r.Log == nil {
+		return nil, errNoHistory
+	}
+
+	c, ok := r.Checkpoints.AtTime(product, t)
+	if !ok {
+		return nil, errNoCheckpoint
+	}
+
+	return r.replay(c, func(msg Message) bool {
+		return !msg.Time.After(t)
+	})
+}
+
+func (r Reconstructor) Step(o *OrderBook) (Message, bool) {
+	if r.Log == nil {
+		return Message{}, false
+	}
+
+	seq, _ := o.position()
+
+	msgs := r.Log.Since(o.coin, seq, 1)
+	if len(msgs) == 0 || !o.apply(msgs[0]) {
+		return Message{}, false
+	}
+
+	return msgs[0], true
+}
+
+func (r Reconstructor) replay(c Checkpoint, until func(Message) bool) (*OrderBook, error) {
+	o := newOfflineBook(c.Product)
+	o.loadOrderBook(c.Snapshot())
+	o.setPosition(c.Sequence, c.Time)
+
+	for _, msg := range r.Log.Since(c.Product, c.Sequence, 0) {
+		if !until(msg) {
+			break
+		}
+
+		if !o.apply(msg) {
+			return nil, fmt.Errorf("Message log gap at sequence %v", msg.Sequence)
+		}
+	}
+
+	return o, nil
+}
+
+type MemoryCheckpointStore struct {
+	lock        sync.Mutex
+	retention   time.Duration
+	limit       int
+	checkpoints map[string][]Checkpoint
+}
+
+func NewMemoryCheckpointStore(retention time.Duration, limit int) *MemoryCheckpointStore {
+	var s MemoryCheckpointStore
+
+	s.retention = retention
+	s.limit = limit
+	s.checkpoints = make(map[string][]Checkpoint)
+
+	return &s
+}
+
+func (s *MemoryCheckpointStore) Save(c Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cps := append(s.checkpoints[c.Product], c)
+
+	i := 0
+	if s.retention > 0 {
+		cutoff := c.Time.Add(-s.retention)
+
+		for i < len(cps)-1 && cps[i].Time.Before(cutoff) {
+			i++
+		}
+	}
+
+	if s.limit > 0 && len(cps)-i > s.limit {
+		i = len(cps) - s.limit
+	}
+
+	if i > 0 {
+		cps = append(make([]Checkpoint, 0, len(cps)-i), cps[i:]...)
+	}
+
+	s.checkpoints[c.Product] = cps
+
+	return nil
+}
+
+func (s *MemoryCheckpointStore) AtSequence(product string, seq int64) (Checkpoint, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cps := s.checkpoints[product]
+	i := sort.Search(len(cps), func(i int) bool {
+		return cps[i].Sequence > seq
+	})
+
+	if i == 0 {
+		return Checkpoint{}, false
+	}
+
+	return cps[i-1], true
+}
+
+func (s *MemoryCheckpointStore) AtTime(product string, t time.Time) (Checkpoint, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	cps := s.checkpoints[product]
+	i := sort.Search(len(cps), func(i int) bool {
+		return cps[i].Time.After(t)
+	})
+
+	if i == 0 {
+		return Checkpoint{}, false
+	}
+
+	return cps[i-1], true
+}
+
+type MemoryMessageLog struct {
+	lock      sync.Mutex
+	retention time.Duration
+	limit     int
+	messages  map[string][]Message
+}
+
+func NewMemoryMessageLog(retention time.Duration, limit int) *MemoryMessageLog {
+	var l MemoryMessageLog
+
+	l.retention = retention
+	l.limit = limit
+	l.messages = make(map[string][]Message)
+
+	return &l
+}
+
+func (l *MemoryMessageLog) Append(msg Message) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	msgs := append(l.messages[msg.ProductId], msg)
+
+	if len(msgs)%1024 == 0 {
+		i := 0
+		if l.retention > 0 {
+			cutoff := msg.Time.Add(-l.retention)
+			i = sort.Search(len(msgs), func(i int) bool {
+				return !msgs[i].Time.Before(cutoff)
+			})
+		}
+
+		if l.limit > 0 && len(msgs)-i > l.limit {
+			i = len(msgs) - l.limit
+		}
+
+		if i > 0 {
+			msgs = append(make([]Message, 0, len(msgs)-i), msgs[i:]...)
+		}
+	}
+
+	l.messages[msg.ProductId] = msgs
+}
+
+func (l *MemoryMessageLog) Since(product string, seq int64, limit int) []Message {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	msgs := l.messages[product]
+	i := sort.Search(len(msgs), func(i int) bool {
+		return msgs[i].Sequence > seq
+	})
+
+	msgs = msgs[i:]
+	if limit > 0 && len(msgs) > limit {
+		msgs = msgs[:limit]
+	}
+
+	return append([]Message{}, msgs...)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+	"testing"
+	"time"
+)
+
+func openMessage(seq int64, t time.Time, side Side, price, size int64) Message {
+	return Message{
+		Type:          TypeOpen,
+		ProductId:     "ETH-USD",
+		Sequence:      seq,
+		Time:          t,
+		Side:          side,
+		OrderId:       fmt.Sprintf("order-%d", seq),
+		Price:         decimal.NewFromInt(price),
+		RemainingSize: decimal.NewFromInt(size),
+	}
+}
+
+func TestMemoryCheckpointStoreLimit(t *testing.T) {
+	s := NewMemoryCheckpointStore(0, 3)
+	base := time.Now()
+
+	for i := int64(1); i <= 5; i++ {
+		s.Save(Checkpoint{Product: "ETH-USD", Sequence: i * 10,
+			Time: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	if _, ok := s.AtSequence("ETH-USD", 25); ok {
+		t.Error("Expected checkpoint 20 to be evicted")
+	}
+
+	c, ok := s.AtSequence("ETH-USD", 35)
+	if !ok || c.Sequence != 30 {
+		t.Errorf("Expected checkpoint 30, got %v %v", c.Sequence, ok)
+	}
+}
+
+func TestMemoryMessageLogLimit(t *testing.T) {
+	l := NewMemoryMessageLog(0, 2048)
+	base := time.Now()
+
+	for i := int64(1); i <= 10000; i++ {
+		l.Append(openMessage(i, base, Buy, 100, 1))
+	}
+
+	msgs := l.Since("ETH-USD", 0, 0)
+	if len(msgs) > 2048+1024 {
+		t.Errorf("Expected at most %v messages, got %v", 2048+1024, len(msgs))
+	}
+
+	if last := msgs[len(msgs)-1].Sequence; last != 10000 {
+		t.Errorf("Expected the newest message to be kept, got %v", last)
+	}
+}
+
+func TestReconstructFromCheckpoint(t *testing.T) {
+	o := newOfflineBook("ETH-USD")
+	o.checkpoints = NewMemoryCheckpointStore(0, DefaultCheckpointLimit)
+	o.log = NewMemoryMessageLog(0, DefaultMessageLogLimit)
+	o.interval = 5 * time.Second
+
+	base := time.Now()
+	for i := int64(1); i <= 20; i++ {
+		side, price := Buy, 100-i%3
+		if i%2 == 0 {
+			side, price = Sell, 101+i%3
+		}
+
+		o.apply(openMessage(i, base.Add(time.Duration(i)*time.Second), side, price, i))
+	}
+
+	b, err := o.Reconstructor().AtSequence("ETH-USD", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Sequence() != 12 {
+		t.Fatalf("Expected sequence 12, got %v", b.Sequence())
+	}
+
+	var want decimal.Decimal
+	for i := int64(1); i <= 12; i++ {
+		want = want.Add(decimal.NewFromInt(i))
+	}
+
+	var got decimal.Decimal
+	for _, side := range []Side{Buy, Sell} {
+		for _, l := range b.Levels(side, 10) {
+			got = got.Add(l.Size)
+		}
+	}
+
+	if !got.Equal(want) {
+		t.Errorf("Expected %v resting, got %v", want, got)
+	}
+
+	if diffs := b.Validate(o.Checkpoint().Snapshot()); len(diffs) == 0 {
+		t.Error("Expected the reconstructed book to differ from the live book")
+	}
+}
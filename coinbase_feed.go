@@ -0,0 +1,326 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LevelThree struct {
+	Sequence int64             `json:"sequence"`
+	Bids     []LevelThreeEntry `json:"bids"`
+	Asks     []LevelThreeEntry `json:"asks"`
+}
+
+type LevelThreeEntry []string
+
+type Product struct {
+	Id             string          `json:"id"`
+	BaseCurrency   string          `json:"base_currency"`
+	QuoteCurrency  string          `json:"quote_currency"`
+	BaseIncrement  decimal.Decimal `json:"base_increment"`
+	QuoteIncrement decimal.Decimal `json:"quote_increment"`
+}
+
+type Sub struct {
+	Type       string   `json:"type"`
+	ProductIds []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+	Signature  string   `json:"signature,omitempty"`
+	Key        string   `json:"key,omitempty"`
+	Passphrase string   `json:"passphrase,omitempty"`
+	Timestamp  string   `json:"timestamp,omitempty"`
+}
+
+type CoinbaseFeed struct {
+	websocketURL string
+	restURL      string
+	client       *http.Client
+	timeout      time.Duration
+	dialer       *websocket.Dialer
+	recorder     *Recorder
+	credentials  Credentials
+
+	writeLock sync.Mutex
+
+	connLock sync.Mutex
+	conn     *websocket.Conn
+	closed   bool
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	productLock sync.Mutex
+	products    map[string]Product
+}
+
+func NewCoinbaseFeed(opts Options) *CoinbaseFeed {
+	var f CoinbaseFeed
+
+	opts = opts.withDefaults()
+
+	f.websocketURL = opts.WebsocketURL
+	f.restURL = strings.TrimSuffix(opts.RestURL, "/")
+	f.client = opts.HTTPClient
+	f.timeout = opts.RequestTimeout
+	f.dialer = opts.Dialer
+	f.recorder = opts.Recorder
+	f.credentials = opts.Credentials
+	f.products = make(map[string]Product)
+	f.ctx, f.cancel = context.WithCancel(context.Background())
+
+	return &f
+}
+
+func (f *CoinbaseFeed) Connect() error {
+	conn, _, err := f.dialer.Dial(f.websocketURL, nil)
+	if err != nil {
+		return err
+	}
+
+	f.connLock.Lock()
+	old := f.conn
+	f.conn = conn
+	f.closed = false
+	if f.ctx.Err() != nil {
+		f.ctx, f.cancel = context.WithCancel(context.Background())
+	}
+	f.connLock.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	return nil
+}
+
+func (f *CoinbaseFeed) Subscribe(products []string, channels []string) error {
+	conn, _ := f.current()
+	if conn == nil {
+		return errNotConnected
+	}
+
+	sub := Sub{Type: "subscribe", ProductIds: products, Channels: channels}
+
+	if f.credentials.Valid() {
+		err := f.credentials.sign(&sub, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	return conn.WriteJSON(sub)
+}
+
+func (f *CoinbaseFeed) Snapshot(product string) (FeedSnapshot, error) {
+	var snap FeedSnapshot
+
+	resp, buf, err := f.get(fmt.Sprintf("%v/products/%v/book?level=3", f.restURL, product))
+	if err != nil {
+		return snap, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return snap, fmt.Errorf("Snapshot request failed: %v", resp.Status)
+	}
+
+	if f.recorder != nil {
+		err = f.recorder.Snapshot(time.Now(), product, buf)
+		if err != nil {
+			return snap, err
+		}
+	}
+
+	snap, err = parseSnapshot(buf)
+	if err != nil {
+		return snap, err
+	}
+
+	p, err := f.Product(product)
+	if err == nil {
+		snap.Increment = p.QuoteIncrement
+	}
+
+	return snap, nil
+}
+
+func (f *CoinbaseFeed) Product(id string) (Product, error) {
+	var p Product
+
+	f.productLock.Lock()
+	p, ok := f.products[id]
+	f.productLock.Unlock()
+
+	if ok {
+		return p, nil
+	}
+
+	resp, buf, err := f.get(fmt.Sprintf("%v/products/%v", f.restURL, id))
+	if err != nil {
+		return p, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return p, fmt.Errorf("Product request failed: %v", resp.Status)
+	}
+
+	err = json.Unmarshal(buf, &p)
+	if err != nil {
+		return p, err
+	}
+
+	f.productLock.Lock()
+	f.products[id] = p
+	f.productLock.Unlock()
+
+	return p, nil
+}
+
+func parseSnapshot(buf []byte) (FeedSnapshot, error) {
+	var snap FeedSnapshot
+	var parsed LevelThree
+
+	err := json.Unmarshal(buf, &parsed)
+	if err != nil {
+		return snap, err
+	}
+
+	snap.Bids, err = parseLevelThree(parsed.Bids, Buy)
+	if err != nil {
+		return snap, err
+	}
+
+	snap.Asks, err = parseLevelThree(parsed.Asks, Sell)
+	if err != nil {
+		return snap, err
+	}
+
+	snap.Sequence = parsed.Sequence
+
+	return snap, nil
+}
+
+func (f *CoinbaseFeed) Read() (Message, error) {
+	var msg Message
+
+	conn, closed := f.current()
+	if conn == nil || closed {
+		return msg, io.EOF
+	}
+
+	_, buf, err := conn.ReadMessage()
+	received := time.Now()
+	if err != nil {
+		_, closed = f.current()
+		if closed || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			return msg, io.EOF
+		}
+
+		return msg, err
+	}
+
+	if f.recorder != nil {
+		err = f.recorder.Frame(time.Now(), buf)
+		if err != nil {
+			return msg, err
+		}
+	}
+
+	msg, err = decodeMessage(buf)
+	msg.Received = received
+
+	return msg, err
+}
+
+func (f *CoinbaseFeed) Close() error {
+	f.connLock.Lock()
+	f.cancel()
+	if f.conn == nil || f.closed {
+		f.connLock.Unlock()
+		return nil
+	}
+	conn := f.conn
+	f.closed = true
+	f.connLock.Unlock()
+
+	f.writeLock.Lock()
+	conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.
+			CloseNormalClosure, ""))
+	f.writeLock.Unlock()
+
+	return conn.Close()
+}
+
+func (f *CoinbaseFeed) get(url string) (*http.Response, []byte, error) {
+	f.connLock.Lock()
+	parent := f.ctx
+	f.connLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(parent, f.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, buf, nil
+}
+
+func (f *CoinbaseFeed) current() (*websocket.Conn, bool) {
+	f.connLock.Lock()
+	defer f.connLock.Unlock()
+
+	return f.conn, f.closed
+}
+
+func parseLevelThree(levels []LevelThreeEntry, side Side) ([]Entry, error) {
+	entries := make([]Entry, 0, len(levels))
+
+	for _, l := range levels {
+		var e Entry
+		var err error
+
+		if len(l) < 3 {
+			return nil, fmt.Errorf("Malformed level three entry: %v", l)
+		}
+
+		e.Price, err = decimal.NewFromString(l[0])
+		if err != nil {
+			return nil, err
+		}
+		e.Size, err = decimal.NewFromString(l[1])
+		if err != nil {
+			return nil, err
+		}
+		e.Id = l[2]
+		e.Side = side
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
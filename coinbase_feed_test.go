@@ -0,0 +1,133 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hangingExchange struct {
+	lock  sync.Mutex
+	hangs int
+	calls int
+
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (e *hangingExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		var upgrader websocket.Upgrader
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+
+	switch r.URL.Path {
+	case "/products/ETH-USD":
+		w.Write([]byte(`{"id":"ETH-USD","quote_increment":"0.01"}`))
+	case "/products/ETH-USD/book":
+		e.lock.Lock()
+		hang := e.calls < e.hangs
+		e.calls++
+		e.lock.Unlock()
+
+		if hang {
+			e.started <- struct{}{}
+			<-r.Context().Done()
+			e.cancelled <- struct{}{}
+			return
+		}
+
+		w.Write([]byte(`{"sequence":10,"bids":[["100.00","1","a"]],"asks":[]}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func hangingServer(hangs int) (*hangingExchange, *httptest.Server, Options) {
+	e := &hangingExchange{
+		hangs:     hangs,
+		started:   make(chan struct{}, hangs),
+		cancelled: make(chan struct{}, hangs),
+	}
+
+	srv := httptest.NewServer(e)
+
+	return e, srv, Options{
+		WebsocketURL: "ws" + strings.TrimPrefix(srv.URL, "http"),
+		RestURL:      srv.URL,
+		ReconnectMin: 10 * time.Millisecond,
+		ReconnectMax: 20 * time.Millisecond,
+	}
+}
+
+func TestSnapshotTimeoutRetries(t *testing.T) {
+	e, srv, opts := hangingServer(1)
+	defer srv.Close()
+
+	opts.RequestTimeout = 100 * time.Millisecond
+
+	m, err := NewBookManager(context.Background(), []string{"ETH-USD"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	waitState(t, m.State, StateLive)
+
+	select {
+	case <-e.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the first snapshot request to be abandoned")
+	}
+
+	if seq := m.Book("ETH-USD").Sequence(); seq != 10 {
+		t.Errorf("Expected sequence 10, got %v", seq)
+	}
+
+	if _, errs := m.counts(); errs != 1 {
+		t.Errorf("Expected 1 error, got %v", errs)
+	}
+}
+
+func TestCloseCancelsSnapshot(t *testing.T) {
+	e, srv, opts := hangingServer(1)
+	defer srv.Close()
+
+	opts.RequestTimeout = time.Minute
+
+	m, err := NewBookManager(context.Background(), []string{"ETH-USD"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-e.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the snapshot request")
+	}
+
+	m.Close()
+
+	select {
+	case <-e.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Close to cancel the snapshot request")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+const (
+	StateConnecting = ConnState(iota)
+	StateLive
+	StateResyncing
+	StateDown
+	StateStale
+)
+
+type ConnState int
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateLive:
+		return "live"
+	case StateResyncing:
+		return "resyncing"
+	case StateDown:
+		return "down"
+	case StateStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+var bpsScale = decimal.New(1, 4)
+
+type Impact struct {
+	Side        Side
+	Size        decimal.Decimal
+	Funds       decimal.Decimal
+	VWAP        decimal.Decimal
+	BestPrice   decimal.Decimal
+	WorstPrice  decimal.Decimal
+	Slippage    decimal.Decimal
+	SlippageBps decimal.Decimal
+	Filled      bool
+}
+
+func (o *OrderBook) DepthTo(side Side, price decimal.Decimal) decimal.Decimal {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	return o.depthTo(side.Opposite(), price)
+}
+
+func (o *OrderBook) PriceAfter(side Side, size decimal.Decimal) (decimal.Decimal, bool) {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	var total decimal.Decimal
+
+	it := o.index(side.Opposite()).Iterator()
+	for it.Next() {
+		l := it.Value()
+
+		total = total.Add(l.size)
+		if !total.LessThan(size) {
+			return l.price, true
+		}
+	}
+
+	return decimal.Zero, false
+}
+
+func (o *OrderBook) MarketImpact(side Side, size decimal.Decimal) Impact {
+	return o.impact(side, size, false)
+}
+
+func (o *OrderBook) MarketImpactFunds(side Side, funds decimal.Decimal) Impact {
+	return o.impact(side, funds, true)
+}
+
+func (o *OrderBook) Liquidity(bps decimal.Decimal) (decimal.Decimal, decimal.Decimal) {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	bid, okBid := o.best(Buy)
+	ask, okAsk := o.best(Sell)
+	if !okBid || !okAsk {
+		return decimal.Zero, decimal.Zero
+	}
+
+	mid := bid.Add(ask).Div(decimal.New(2, 0))
+	offset := mid.Mul(bps).Div(bpsScale)
+
+	return o.depthTo(Buy, mid.Sub(offset)), o.depthTo(Sell, mid.Add(offset))
+}
+
+func (o *OrderBook) impact(side Side, amount decimal.Decimal, funds bool) Impact {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	var im Impact
+
+	im.Side = side
+	im.Filled = !amount.IsPositive()
+
+	it := o.index(side.Opposite()).Iterator()
+	for amount.IsPositive() && it.Next() {
+		l := it.Value()
+
+		if im.BestPrice.IsZero() {
+			im.BestPrice = l.price
+		}
+
+		im.WorstPrice = l.price
+
+		available := l.size
+		if funds {
+			available = l.size.Mul(l.price)
+		}
+
+		if !available.LessThan(amount) {
+			if funds {
+				im.Size = im.Size.Add(amount.Div(l.price))
+				im.Funds = im.Funds.Add(amount)
+			} else {
+				im.Size = im.Size.Add(amount)
+				im.Funds = im.Funds.Add(amount.Mul(l.price))
+			}
+
+			im.Filled = true
+			break
+		}
+
+		im.Size = im.Size.Add(l.size)
+		im.Funds = im.Funds.Add(l.size.Mul(l.price))
+		amount = amount.Sub(available)
+	}
+
+	if im.Size.IsZero() {
+		return im
+	}
+
+	im.VWAP = im.Funds.Div(im.Size)
+
+	im.Slippage = im.VWAP.Sub(im.BestPrice)
+	if side == Sell {
+		im.Slippage = im.Slippage.Neg()
+	}
+
+	im.SlippageBps = im.Slippage.Div(im.BestPrice).Mul(bpsScale)
+
+	return im
+}
+
+func (o *OrderBook) depthTo(side Side, price decimal.Decimal) decimal.Decimal {
+	var total decimal.Decimal
+
+	it := o.index(side).Iterator()
+	for it.Next() {
+		l := it.Value()
+		if worse(side, l.price, price) {
+			break
+		}
+
+		total = total.Add(l.size)
+	}
+
+	return total
+}
+
+func (o *OrderBook) best(side Side) (decimal.Decimal, bool) {
+	it := o.index(side).Iterator()
+	if !it.Next() {
+		return decimal.Zero, false
+	}
+
+	return it.Value().price, true
+}
+
+func worse(side Side, price, limit decimal.Decimal) bool {
+	if side == Buy {
+		return price.LessThan(limit)
+	}
+
+	return price.GreaterThan(limit)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"testing"
+)
+
+func depthBook() *OrderBook {
+	o := newOfflineBook("ETH-USD")
+
+	o.loadOrderBook(FeedSnapshot{Sequence: 1,
+		Bids: []Entry{
+			{Id: "b1", Side: Buy, Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(1)},
+			{Id: "b2", Side: Buy, Price: decimal.NewFromInt(98), Size: decimal.NewFromInt(2)},
+		},
+		Asks: []Entry{
+			{Id: "a1", Side: Sell, Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(3)},
+			{Id: "a2", Side: Sell, Price: decimal.NewFromInt(102), Size: decimal.NewFromInt(4)},
+		},
+	})
+
+	return o
+}
+
+func TestDepthUsesTakerSide(t *testing.T) {
+	o := depthBook()
+
+	tests := []struct {
+		side  Side
+		limit int64
+		size  int64
+		depth int64
+		price int64
+	}{
+		{Buy, 102, 5, 7, 102},
+		{Buy, 101, 3, 3, 101},
+		{Sell, 98, 2, 3, 98},
+		{Sell, 99, 1, 1, 99},
+	}
+
+	for _, tt := range tests {
+		depth := o.DepthTo(tt.side, decimal.NewFromInt(tt.limit))
+		if !depth.Equal(decimal.NewFromInt(tt.depth)) {
+			t.Errorf("DepthTo(%v, %v) = %v, expected %v", tt.side, tt.limit, depth, tt.depth)
+		}
+
+		price, ok := o.PriceAfter(tt.side, decimal.NewFromInt(tt.size))
+		if !ok || !price.Equal(decimal.NewFromInt(tt.price)) {
+			t.Errorf("PriceAfter(%v, %v) = %v %v, expected %v", tt.side, tt.size,
+				price, ok, tt.price)
+		}
+
+		im := o.MarketImpact(tt.side, decimal.NewFromInt(tt.size))
+		if !im.Filled || !im.WorstPrice.Equal(price) {
+			t.Errorf("MarketImpact(%v, %v) worst price %v, expected %v", tt.side,
+				tt.size, im.WorstPrice, price)
+		}
+	}
+
+	if _, ok := o.PriceAfter(Buy, decimal.NewFromInt(8)); ok {
+		t.Error("Expected PriceAfter to fail beyond the resting size")
+	}
+}
+
+func TestMarketImpactFunds(t *testing.T) {
+	o := newOfflineBook("ETH-USD")
+	o.loadOrderBook(FeedSnapshot{Sequence: 1,
+		Bids: []Entry{
+			{Id: "b1", Side: Buy, Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(100)},
+			{Id: "b2", Side: Buy, Price: decimal.NewFromInt(2), Size: decimal.NewFromInt(100)},
+		},
+		Asks: []Entry{
+			{Id: "a1", Side: Sell, Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(100)},
+			{Id: "a2", Side: Sell, Price: decimal.NewFromInt(4), Size: decimal.NewFromInt(100)},
+		},
+	})
+
+	tests := []struct {
+		side   Side
+		funds  string
+		filled bool
+		worst  int64
+		size   string
+	}{
+		{Buy, "100", true, 3, "33.3333333333333333"},
+		{Buy, "300", true, 3, "100"},
+		{Buy, "500", true, 4, "150"},
+		{Buy, "800", false, 4, "200"},
+		{Sell, "100", true, 3, "33.3333333333333333"},
+		{Sell, "400", true, 2, "150"},
+	}
+
+	for _, tt := range tests {
+		im := o.MarketImpactFunds(tt.side, decimal.RequireFromString(tt.funds))
+
+		if im.Filled != tt.filled || !im.WorstPrice.Equal(decimal.NewFromInt(tt.worst)) ||
+			im.Size.String() != tt.size {
+			t.Errorf("MarketImpactFunds(%v, %v) = filled %v worst %v size %v, expected %v %v %v",
+				tt.side, tt.funds, im.Filled, im.WorstPrice, im.Size,
+				tt.filled, tt.worst, tt.size)
+		}
+
+		if tt.filled && !im.Funds.Equal(decimal.RequireFromString(tt.funds)) {
+			t.Errorf("MarketImpactFunds(%v, %v) used %v", tt.side, tt.funds, im.Funds)
+		}
+	}
+}
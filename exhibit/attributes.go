@@ -31,6 +31,8 @@ const (
 	BGWhite
 )
 
+const bgDefault = BackgroundColor(49)
+
 type Attributes struct {
 	ForegroundColor ForegroundColor
 	BackgroundColor BackgroundColor
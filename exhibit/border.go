@@ -31,6 +31,7 @@ type Border struct {
 	Box
 	Attributes
 	Visible bool
+	Title   string
 }
 
 type Style int
@@ -77,7 +78,9 @@ func (b Border) Cell(p image.Point, r image.Rectangle) (Cell, bool) {
 		return c, false
 	}
 
-	if p.X == r.Min.X && p.Y == r.Min.Y {
+	if t, ok := b.titleRune(p, r); ok {
+		c.Value = t
+	} else if p.X == r.Min.X && p.Y == r.Min.Y {
 		c.Value = BorderRune(TopLeft, b.Style)
 	} else if p.X == r.Max.X-1 && p.Y == r.Min.Y {
 		c.Value = BorderRune(TopRight, b.Style)
@@ -93,3 +96,18 @@ func (b Border) Cell(p image.Point, r image.Rectangle) (Cell, bool) {
 
 	return c, true
 }
+
+func (b Border) titleRune(p image.Point, r image.Rectangle) (rune, bool) {
+	if b.Title == "" || p.Y != r.Min.Y {
+		return 0, false
+	}
+
+	title := []rune(" " + b.Title + " ")
+	i := p.X - r.Min.X - 2
+
+	if i < 0 || i >= len(title) || p.X >= r.Max.X-2 {
+		return 0, false
+	}
+
+	return title[i], true
+}
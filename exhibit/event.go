@@ -1,8 +1,17 @@
 package exhibit
 
 const (
-	EventCtrC = Event(3)
-	Eventq    = Event(113)
+	EventCtrC     = Event(3)
+	EventB        = Event(66)
+	EventS        = Event(83)
+	EventLBracket = Event(91)
+	EventRBracket = Event(93)
+	Eventb        = Event(98)
+	Eventc        = Event(99)
+	Eventn        = Event(110)
+	Eventp        = Event(112)
+	Eventq        = Event(113)
+	Events        = Event(115)
 )
 
 type Event byte
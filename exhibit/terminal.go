@@ -2,10 +2,10 @@ package exhibit
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"io"
-	"log"
 	"os"
 	"sync"
 	"time"
@@ -49,36 +49,42 @@ type Terminal struct {
 	doneLock sync.Mutex
 	done     bool
 
-	shutdown   chan struct{}
+	cancel   context.CancelFunc
+	finished chan struct{}
+
+	termLock sync.Mutex
+	termErr  error
+
 	event      chan Event
 	sizeChange chan image.Point
 }
 
-func Init() *Terminal {
+func Init(ctx context.Context) (*Terminal, error) {
 	var t Terminal
 
 	out, err := os.OpenFile("/dev/tty", os.O_WRONLY|unix.O_NOCTTY, os.ModeCharDevice)
 	if err != nil {
-		log.Panic(err)
+		return nil, err
 	}
 
 	in, err := os.OpenFile("/dev/tty", os.O_RDONLY|unix.O_NOCTTY, os.ModeCharDevice)
 	if err != nil {
-		log.Panic(err)
+		out.Close()
+		return nil, err
 	}
 
 	termios, err := unix.IoctlGetTermios(int(out.Fd()), ioctlReadTermios)
 	if err != nil {
 		out.Close()
 		in.Close()
-		log.Panic(err)
+		return nil, err
 	}
 
 	t.buffer = &bytes.Buffer{}
 	t.out = out
 	t.in = in
 	t.termios = *termios
-	t.shutdown = make(chan struct{})
+	t.finished = make(chan struct{})
 	t.event = make(chan Event, 1024)
 	t.Event = t.event
 	t.sizeChange = make(chan image.Point, 32)
@@ -88,33 +94,76 @@ func Init() *Terminal {
 	t.setSize(size)
 
 	t.enterAlt()
-	t.enterRaw()
+	err = t.enterRaw()
+	if err != nil {
+		t.exitAlt()
+		t.out.Close()
+		t.in.Close()
+		return nil, err
+	}
+
 	t.SetCursor(0, 0)
 	t.Clear()
 	t.Render()
 
-	t.watchSize()
+	var inner context.Context
+	inner, t.cancel = context.WithCancel(ctx)
+
+	t.watchSize(inner)
 	t.watchInput()
+	t.watchContext(ctx, inner)
 
-	return &t
+	return &t, nil
 }
 
-func (t *Terminal) Shutdown() {
+func (t *Terminal) Close() error {
+	t.cancel()
+
+	return t.Wait()
+}
+
+func (t *Terminal) Wait() error {
+	<-t.finished
+
+	t.termLock.Lock()
+	defer t.termLock.Unlock()
+
+	return t.termErr
+}
+
+func (t *Terminal) watchContext(parent, ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+
+		err := t.restore()
+		if err == nil {
+			err = parent.Err()
+		}
+
+		t.termLock.Lock()
+		t.termErr = err
+		t.termLock.Unlock()
+
+		close(t.finished)
+	}()
+}
+
+func (t *Terminal) restore() error {
 	t.doneLock.Lock()
 	t.done = true
 	t.doneLock.Unlock()
 
 	t.resetBuffer()
 	t.ShowCursor()
-	t.Render()
+	t.flush()
 
-	t.exitRaw()
+	err := t.exitRaw()
 	t.exitAlt()
 
 	t.out.Close()
 	t.in.Close()
 
-	t.shutdown <- struct{}{}
+	return err
 }
 
 func (t *Terminal) Clear() {
@@ -271,7 +320,12 @@ func (t *Terminal) writeAttrs(attrs Attributes) {
 	}
 
 	if t.currentAttributes.BackgroundColor != attrs.BackgroundColor {
-		t.writeBuffer([]byte(fmt.Sprintf(sgr, attrs.BackgroundColor)))
+		bg := attrs.BackgroundColor
+		if bg == 0 {
+			bg = bgDefault
+		}
+
+		t.writeBuffer([]byte(fmt.Sprintf(sgr, bg)))
 		t.currentAttributes.BackgroundColor = attrs.BackgroundColor
 	}
 }
@@ -296,24 +350,20 @@ func (t *Terminal) watchInput() {
 	}()
 }
 
-func (t *Terminal) watchSize() {
+func (t *Terminal) watchSize(ctx context.Context) {
 	go func() {
 		timer := time.NewTicker(1 * time.Second)
+		defer timer.Stop()
 
 		for {
 			select {
-			case <-t.shutdown:
-				timer.Stop()
+			case <-ctx.Done():
 				close(t.sizeChange)
-				break
+				return
 			case <-timer.C:
 				size := t.sizeInternal()
 				t.setSize(size)
-
-				continue
 			}
-
-			break
 		}
 	}()
 }
@@ -351,7 +401,7 @@ func (t *Terminal) exitAlt() {
 	t.resetBuffer()
 }
 
-func (t *Terminal) enterRaw() {
+func (t *Terminal) enterRaw() error {
 	termios := t.termios
 
 	termios.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP |
@@ -367,20 +417,9 @@ func (t *Terminal) enterRaw() {
 	termios.Cc[unix.VMIN] = 1
 	termios.Cc[unix.VTIME] = 0
 
-	err := unix.IoctlSetTermios(t.outFd(), ioctlWriteTermios, &termios)
-	if err != nil {
-		t.exitAlt()
-		t.out.Close()
-		t.in.Close()
-		log.Panic(err)
-	}
+	return unix.IoctlSetTermios(t.outFd(), ioctlWriteTermios, &termios)
 }
 
-func (t *Terminal) exitRaw() {
-	err := unix.IoctlSetTermios(t.outFd(), ioctlWriteTermios, &t.termios)
-	if err != nil {
-		t.out.Close()
-		t.in.Close()
-		log.Panic(err)
-	}
+func (t *Terminal) exitRaw() error {
+	return unix.IoctlSetTermios(t.outFd(), ioctlWriteTermios, &t.termios)
 }
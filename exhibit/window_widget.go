@@ -27,7 +27,7 @@ func (w *WindowWidget) AddWidget(widget Widget) {
 	w.widgets = append(w.widgets, widget)
 }
 
-func (w WindowWidget) Size() image.Point {
+func (w *WindowWidget) Size() image.Point {
 	w.blockLock.Lock()
 	defer w.blockLock.Unlock()
 
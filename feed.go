@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errNotConnected = errors.New("Feed not connected")
+var errStale = errors.New("Feed is stale")
+
+type Feed interface {
+	Connect() error
+	Subscribe(products []string, channels []string) error
+	Snapshot(product string) (FeedSnapshot, error)
+	Read() (Message, error)
+	Close() error
+}
+
+type Message struct {
+	Sequence      int64           `json:"sequence"`
+	Type          MessageType     `json:"type"`
+	Side          Side            `json:"side"`
+	Price         decimal.Decimal `json:"price"`
+	Size          decimal.Decimal `json:"size"`
+	OrderId       string          `json:"order_id"`
+	MakerOrderId  string          `json:"maker_order_id"`
+	TakerOrderId  string          `json:"taker_order_id"`
+	TradeId       int64           `json:"trade_id"`
+	LastTradeId   int64           `json:"last_trade_id"`
+	RemainingSize decimal.Decimal `json:"remaining_size"`
+	NewSize       decimal.Decimal `json:"new_size"`
+	OldSize       decimal.Decimal `json:"old_size"`
+	Funds         decimal.Decimal `json:"funds"`
+	NewFunds      decimal.Decimal `json:"new_funds"`
+	OldFunds      decimal.Decimal `json:"old_funds"`
+	StopType      StopType        `json:"stop_type"`
+	StopPrice     decimal.Decimal `json:"stop_price"`
+	TakerFeeRate  decimal.Decimal `json:"taker_fee_rate"`
+	Private       bool            `json:"private"`
+	UserId        string          `json:"user_id"`
+	ProfileId     string          `json:"profile_id"`
+	ProductId     string          `json:"product_id"`
+	Time          time.Time       `json:"time"`
+	Timestamp     decimal.Decimal `json:"timestamp"`
+	Reason        DoneReason      `json:"reason"`
+	OrderType     OrderType       `json:"order_type"`
+	ClientOid     string          `json:"client_oid"`
+	Bids          []LevelTwoEntry `json:"bids"`
+	Asks          []LevelTwoEntry `json:"asks"`
+	Changes       []LevelTwoEntry `json:"changes"`
+	Message       string          `json:"message"`
+	Received      time.Time       `json:"-"`
+}
+
+func decodeMessage(buf []byte) (Message, error) {
+	var msg Message
+
+	err := json.Unmarshal(buf, &msg)
+	if err != nil {
+		return msg, &DecodeError{err, buf}
+	}
+
+	if msg.Time.IsZero() && !msg.Timestamp.IsZero() {
+		sec := msg.Timestamp.IntPart()
+		nsec := msg.Timestamp.Sub(decimal.New(sec, 0)).Shift(9).IntPart()
+		msg.Time = time.Unix(sec, nsec).UTC()
+	}
+
+	return msg, nil
+}
+
+type DecodeError struct {
+	Err  error
+	Data []byte
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Decoding %s: %v", e.Data, e.Err)
+}
+
+type FeedSnapshot struct {
+	Sequence  int64
+	Increment decimal.Decimal
+	Bids      []Entry
+	Asks      []Entry
+}
@@ -3,7 +3,6 @@ module git.cotugno.family/kevin/spectator
 go 1.15
 
 require (
-	github.com/emirpasic/gods v1.12.0
 	github.com/gorilla/websocket v1.4.2
 	github.com/shopspring/decimal v1.2.0
 	golang.org/x/sys v0.0.0-20200928205150-006507a75852
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func runHeadless(ctx context.Context, opts Options) error {
+	var err error
+
+	ob, err = NewOrderBook(ctx, coin, opts)
+	if err != nil {
+		return err
+	}
+
+	if *metricsAddr != "" {
+		err = serveMetrics(*metricsAddr)
+		if err != nil {
+			ob.Close()
+			return err
+		}
+	}
+
+	if *apiAddr != "" {
+		err = serveAPI(*apiAddr)
+		if err != nil {
+			ob.Close()
+			return err
+		}
+
+		sub := ob.Subscribe(DefaultSubscriptionBuffer, PolicyDropOldest)
+		go func() {
+			for msg := range sub.C {
+				if msg.Type == TypeMatch {
+					addTrade(msg)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for err := range ob.Err {
+			log.Println(err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case <-signals:
+		return ob.Close()
+	case <-ctx.Done():
+		return ob.Wait()
+	}
+}
+
+func serveMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Metrics{Books: []*OrderBook{ob}, Render: &renderTimes})
+
+	go http.Serve(ln, mux)
+
+	return nil
+}
+
+func serveAPI(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go http.Serve(ln, API{Book: ob, Trades: trades})
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	histogramBase    = 100 * time.Microsecond
+	histogramBuckets = 21
+)
+
+type HistogramSummary struct {
+	Count int64
+	Mean  time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+type Histogram struct {
+	lock   sync.Mutex
+	counts [histogramBuckets + 1]int64
+	count  int64
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func bucketBound(i int) time.Duration {
+	return histogramBase << uint(i)
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	i := 0
+	for i < histogramBuckets && d > bucketBound(i) {
+		i++
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.counts[i]++
+	h.sum += d
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+
+	h.count++
+}
+
+func (h *Histogram) Summary() HistogramSummary {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var s HistogramSummary
+
+	if h.count == 0 {
+		return s
+	}
+
+	s.Count = h.count
+	s.Mean = h.sum / time.Duration(h.count)
+	s.Min = h.min
+	s.Max = h.max
+	s.P50 = h.quantile(0.5)
+	s.P90 = h.quantile(0.9)
+	s.P99 = h.quantile(0.99)
+
+	return s
+}
+
+func (h *Histogram) Buckets() ([]time.Duration, []int64, time.Duration, int64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	bounds := make([]time.Duration, histogramBuckets)
+	cumulative := make([]int64, histogramBuckets)
+
+	var total int64
+	for i := 0; i < histogramBuckets; i++ {
+		total += h.counts[i]
+
+		bounds[i] = bucketBound(i)
+		cumulative[i] = total
+	}
+
+	return bounds, cumulative, h.sum, h.count
+}
+
+func (h *Histogram) quantile(q float64) time.Duration {
+	target := int64(q * float64(h.count))
+	if target < 1 {
+		target = 1
+	}
+
+	var total int64
+	for i, c := range h.counts {
+		total += c
+		if total < target {
+			continue
+		}
+
+		if i == histogramBuckets || bucketBound(i) > h.max {
+			return h.max
+		}
+
+		return bucketBound(i)
+	}
+
+	return h.max
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"container/list"
+)
+
+type Level struct {
+	Price decimal.Decimal
+	Size  decimal.Decimal
+	Count int
+	Own   decimal.Decimal
+}
+
+type priceLevel struct {
+	price  decimal.Decimal
+	tick   int64
+	size   decimal.Decimal
+	own    decimal.Decimal
+	orders *list.List
+	index  map[string]*list.Element
+}
+
+func newPriceLevel(price decimal.Decimal, tick int64) *priceLevel {
+	var l priceLevel
+
+	l.price = price
+	l.tick = tick
+	l.orders = list.New()
+	l.index = make(map[string]*list.Element)
+
+	return &l
+}
+
+func (l *priceLevel) Level() Level {
+	return Level{Price: l.price, Size: l.size, Count: l.orders.Len(), Own: l.own}
+}
+
+func (l *priceLevel) Get(id string) (Entry, bool) {
+	el, ok := l.index[id]
+	if !ok {
+		return Entry{}, false
+	}
+
+	return el.Value.(Entry), true
+}
+
+func (l *priceLevel) Set(e Entry) {
+	el, ok := l.index[e.Id]
+	if ok {
+		old := el.Value.(Entry)
+
+		l.size = l.size.Sub(old.Size).Add(e.Size)
+		if old.Own {
+			l.own = l.own.Sub(old.Size)
+		}
+		if e.Own {
+			l.own = l.own.Add(e.Size)
+		}
+
+		el.Value = e
+		return
+	}
+
+	l.size = l.size.Add(e.Size)
+	if e.Own {
+		l.own = l.own.Add(e.Size)
+	}
+
+	l.index[e.Id] = l.orders.PushBack(e)
+}
+
+func (l *priceLevel) Remove(id string) {
+	el, ok := l.index[id]
+	if !ok {
+		return
+	}
+
+	e := el.Value.(Entry)
+
+	l.size = l.size.Sub(e.Size)
+	if e.Own {
+		l.own = l.own.Sub(e.Size)
+	}
+
+	l.orders.Remove(el)
+	delete(l.index, id)
+}
+
+func (l *priceLevel) Position(id string) (int, decimal.Decimal, bool) {
+	var ahead decimal.Decimal
+
+	i := 0
+	for el := l.orders.Front(); el != nil; el = el.Next() {
+		e := el.Value.(Entry)
+		if e.Id == id {
+			return i, ahead, true
+		}
+
+		ahead = ahead.Add(e.Size)
+		i++
+	}
+
+	return 0, decimal.Zero, false
+}
+
+func (l *priceLevel) SetSize(size decimal.Decimal) {
+	l.size = size
+}
+
+func (l *priceLevel) Empty() bool {
+	return l.orders.Len() == 0 && l.size.IsZero()
+}
+
+func (l *priceLevel) Entries() Entries {
+	entries := make(Entries, l.orders.Len())
+
+	for el := l.orders.Front(); el != nil; el = el.Next() {
+		e := el.Value.(Entry)
+		entries[e.Id] = e
+	}
+
+	return entries
+}
+
+func (l *priceLevel) Orders() []Entry {
+	orders := make([]Entry, 0, l.orders.Len())
+
+	for el := l.orders.Front(); el != nil; el = el.Next() {
+		orders = append(orders, el.Value.(Entry))
+	}
+
+	return orders
+}
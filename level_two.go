@@ -0,0 +1,140 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ModeFull = Mode(iota)
+	ModeLevel2
+)
+
+type Mode int
+
+type LevelTwoEntry struct {
+	Side  Side
+	Price decimal.Decimal
+	Size  decimal.Decimal
+}
+
+func (e *LevelTwoEntry) UnmarshalJSON(b []byte) error {
+	var fields []string
+	var err error
+
+	err = json.Unmarshal(b, &fields)
+	if err != nil {
+		return err
+	}
+
+	switch len(fields) {
+	case 2:
+	case 3:
+		e.Side, err = ParseSide(fields[0])
+		if err != nil {
+			return err
+		}
+
+		fields = fields[1:]
+	default:
+		return fmt.Errorf("Malformed level two entry: %v", fields)
+	}
+
+	e.Price, err = decimal.NewFromString(fields[0])
+	if err != nil {
+		return err
+	}
+
+	e.Size, err = decimal.NewFromString(fields[1])
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *OrderBook) Levels(side Side, count int) []Level {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	return o.levels(side, count)
+}
+
+func (o *OrderBook) levels(side Side, count int) []Level {
+	levels := make([]Level, 0)
+
+	it := o.index(side).Iterator()
+	for i := 0; i < count && it.Next(); i++ {
+		levels = append(levels, it.Value().Level())
+	}
+
+	return levels
+}
+
+func (o *OrderBook) applyLevelTwo(msg Message) {
+	o.bookLock.Lock()
+	synced := o.updateLevelTwo(msg)
+	o.bookLock.Unlock()
+
+	o.stats.observe(msg, time.Now())
+
+	if synced {
+		o.manager.synced()
+	}
+
+	o.publish(msg)
+}
+
+func (o *OrderBook) updateLevelTwo(msg Message) bool {
+	switch msg.Type {
+	case TypeSnapshot:
+		o.clear()
+
+		for _, b := range msg.Bids {
+			o.setLevel(Buy, b.Price, b.Size)
+		}
+
+		for _, a := range msg.Asks {
+			o.setLevel(Sell, a.Price, a.Size)
+		}
+
+		o.syncing = false
+		o.setPosition(0, msg.Time)
+
+		return true
+	case TypeL2Update:
+		for _, c := range msg.Changes {
+			o.setLevel(c.Side, c.Price, c.Size)
+		}
+
+		o.setPosition(0, msg.Time)
+	case TypeSubscriptions, TypeError:
+	case TypeReceived, TypeOpen, TypeDone, TypeMatch, TypeChange, TypeActivate:
+		o.sendError(fmt.Errorf("Unexpected %v message in level two mode", msg.Type))
+	default:
+		o.sendError(fmt.Errorf("Unknown message type: %v", msg.Type))
+	}
+
+	return false
+}
+
+func (o *OrderBook) setLevel(side Side, price, size decimal.Decimal) {
+	index := o.index(side)
+	tick := o.tick(price)
+
+	if size.IsZero() {
+		index.Remove(tick)
+		return
+	}
+
+	l, ok := index.Get(tick)
+	if !ok {
+		l = newPriceLevel(price, tick)
+		index.Put(tick, l)
+	}
+
+	l.SetSize(size)
+}
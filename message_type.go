@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	TypeReceived      = MessageType("received")
+	TypeOpen          = MessageType("open")
+	TypeDone          = MessageType("done")
+	TypeMatch         = MessageType("match")
+	TypeChange        = MessageType("change")
+	TypeActivate      = MessageType("activate")
+	TypeSnapshot      = MessageType("snapshot")
+	TypeL2Update      = MessageType("l2update")
+	TypeSubscriptions = MessageType("subscriptions")
+	TypeError         = MessageType("error")
+	TypeHeartbeat     = MessageType("heartbeat")
+)
+
+var messageTypes = []MessageType{TypeReceived, TypeOpen, TypeDone,
+	TypeMatch, TypeChange, TypeActivate, TypeSnapshot, TypeL2Update,
+	TypeSubscriptions, TypeError, TypeHeartbeat}
+
+type MessageType string
+
+func ParseMessageType(s string) (MessageType, error) {
+	for _, t := range messageTypes {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unknown message type: %q", s)
+}
+
+func (t MessageType) String() string {
+	return string(t)
+}
+
+func (t MessageType) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func (t *MessageType) UnmarshalText(b []byte) error {
+	mt, err := ParseMessageType(string(b))
+	if err != nil {
+		return err
+	}
+
+	*t = mt
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var metricsDepthBps = []int64{10, 50, 100}
+
+type Metrics struct {
+	Books  []*OrderBook
+	Render *Histogram
+}
+
+type bookMetrics struct {
+	product string
+	snap    BookSnapshot
+	stats   Stats
+	bids    []decimal.Decimal
+	asks    []decimal.Decimal
+}
+
+func (m Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+
+	m.write(&buf)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+func (m Metrics) write(buf *bytes.Buffer) {
+	books := make([]bookMetrics, 0, len(m.Books))
+	for _, o := range m.Books {
+		b := bookMetrics{product: o.Product(), snap: o.Snapshot(1), stats: o.Stats()}
+
+		for _, bps := range metricsDepthBps {
+			bid, ask := o.Liquidity(decimal.NewFromInt(bps))
+			b.bids = append(b.bids, bid)
+			b.asks = append(b.asks, ask)
+		}
+
+		books = append(books, b)
+	}
+
+	header(buf, "spectator_best_bid", "gauge", "Best bid price.")
+	for _, b := range books {
+		if l, ok := b.snap.BestBid(); ok {
+			sample(buf, "spectator_best_bid", l.Price.String(), "product", b.product)
+		}
+	}
+
+	header(buf, "spectator_best_ask", "gauge", "Best ask price.")
+	for _, b := range books {
+		if l, ok := b.snap.BestAsk(); ok {
+			sample(buf, "spectator_best_ask", l.Price.String(), "product", b.product)
+		}
+	}
+
+	header(buf, "spectator_spread", "gauge", "Best ask minus best bid.")
+	for _, b := range books {
+		sample(buf, "spectator_spread", b.snap.Spread().String(), "product", b.product)
+	}
+
+	header(buf, "spectator_sequence", "gauge", "Last applied feed sequence.")
+	for _, b := range books {
+		sample(buf, "spectator_sequence", strconv.FormatInt(b.snap.Sequence, 10),
+			"product", b.product)
+	}
+
+	header(buf, "spectator_depth", "gauge", "Size resting within bps of the mid price.")
+	for _, b := range books {
+		for i, bps := range metricsDepthBps {
+			n := strconv.FormatInt(bps, 10)
+
+			sample(buf, "spectator_depth", b.bids[i].String(),
+				"product", b.product, "side", Buy.String(), "bps", n)
+			sample(buf, "spectator_depth", b.asks[i].String(),
+				"product", b.product, "side", Sell.String(), "bps", n)
+		}
+	}
+
+	header(buf, "spectator_messages_total", "counter", "Feed messages applied by type.")
+	for _, b := range books {
+		types := make([]string, 0, len(b.stats.ByType))
+		for t := range b.stats.ByType {
+			types = append(types, string(t))
+		}
+		sort.Strings(types)
+
+		for _, t := range types {
+			sample(buf, "spectator_messages_total",
+				strconv.FormatInt(b.stats.ByType[MessageType(t)], 10),
+				"product", b.product, "type", t)
+		}
+	}
+
+	header(buf, "spectator_messages_per_second", "gauge", "Feed messages applied per second.")
+	for _, b := range books {
+		sample(buf, "spectator_messages_per_second",
+			strconv.FormatFloat(b.stats.Rate, 'f', -1, 64), "product", b.product)
+	}
+
+	counter(buf, books, "spectator_gaps_total", "Sequence gaps detected.",
+		func(st Stats) int64 { return st.Gaps })
+	counter(buf, books, "spectator_syncs_total", "Snapshot syncs after connecting.",
+		func(st Stats) int64 { return st.Syncs })
+	counter(buf, books, "spectator_resyncs_total", "Snapshot resyncs after a gap or failed check.",
+		func(st Stats) int64 { return st.Resyncs })
+	counter(buf, books, "spectator_reconnects_total", "Feed reconnects.",
+		func(st Stats) int64 { return st.Reconnects })
+	counter(buf, books, "spectator_errors_total", "Errors reported on Err.",
+		func(st Stats) int64 { return st.Errors })
+
+	header(buf, "spectator_feed_latency_seconds", "histogram",
+		"Exchange time to local receive.")
+	for _, o := range m.Books {
+		histogram(buf, "spectator_feed_latency_seconds", &o.stats.feed,
+			"product", o.Product())
+	}
+
+	header(buf, "spectator_apply_latency_seconds", "histogram",
+		"Local receive to applied to the book.")
+	for _, o := range m.Books {
+		histogram(buf, "spectator_apply_latency_seconds", &o.stats.apply,
+			"product", o.Product())
+	}
+
+	if m.Render != nil {
+		header(buf, "spectator_render_seconds", "histogram", "Time to render a frame.")
+		histogram(buf, "spectator_render_seconds", m.Render)
+	}
+}
+
+func counter(buf *bytes.Buffer, books []bookMetrics, name, help string,
+	value func(Stats) int64) {
+	header(buf, name, "counter", help)
+
+	for _, b := range books {
+		sample(buf, name, strconv.FormatInt(value(b.stats), 10), "product", b.product)
+	}
+}
+
+func histogram(buf *bytes.Buffer, name string, h *Histogram, labels ...string) {
+	bounds, counts, sum, count := h.Buckets()
+
+	bucket := func(le string) []string {
+		return append(append([]string{}, labels...), "le", le)
+	}
+
+	for i, b := range bounds {
+		sample(buf, name+"_bucket", strconv.FormatInt(counts[i], 10), bucket(seconds(b))...)
+	}
+
+	sample(buf, name+"_bucket", strconv.FormatInt(count, 10), bucket("+Inf")...)
+	sample(buf, name+"_sum", seconds(sum), labels...)
+	sample(buf, name+"_count", strconv.FormatInt(count, 10), labels...)
+}
+
+func header(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+}
+
+func sample(buf *bytes.Buffer, name, value string, labels ...string) {
+	buf.WriteString(name)
+
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, "%v=%q", labels[i], labels[i+1])
+		}
+		buf.WriteByte('}')
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
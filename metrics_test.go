@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsScrape(t *testing.T) {
+	o := depthBook()
+
+	now := time.Now()
+	for i, id := range []string{"b3", "a3"} {
+		side, price := Buy, int64(97)
+		if i == 1 {
+			side, price = Sell, 103
+		}
+
+		o.apply(Message{Type: TypeOpen, ProductId: "ETH-USD", Sequence: int64(2 + i),
+			Side: side, OrderId: id, Price: decimal.NewFromInt(price),
+			RemainingSize: decimal.NewFromInt(5),
+			Time:          now.Add(-2 * time.Millisecond), Received: now})
+	}
+
+	var render Histogram
+	render.Observe(3 * time.Millisecond)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Metrics{Books: []*OrderBook{o}, Render: &render})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain, got %v", ct)
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make(map[string]string)
+	for _, line := range strings.Split(string(buf), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.LastIndex(line, " ")
+		samples[line[:i]] = line[i+1:]
+	}
+
+	expected := map[string]string{
+		`spectator_best_bid{product="ETH-USD"}`:                                "99",
+		`spectator_best_ask{product="ETH-USD"}`:                                "101",
+		`spectator_spread{product="ETH-USD"}`:                                  "2",
+		`spectator_sequence{product="ETH-USD"}`:                                "3",
+		`spectator_depth{product="ETH-USD",side="buy",bps="10"}`:               "0",
+		`spectator_depth{product="ETH-USD",side="buy",bps="100"}`:              "1",
+		`spectator_depth{product="ETH-USD",side="sell",bps="100"}`:             "3",
+		`spectator_messages_total{product="ETH-USD",type="open"}`:              "2",
+		`spectator_gaps_total{product="ETH-USD"}`:                              "0",
+		`spectator_syncs_total{product="ETH-USD"}`:                             "0",
+		`spectator_resyncs_total{product="ETH-USD"}`:                           "0",
+		`spectator_feed_latency_seconds_bucket{product="ETH-USD",le="0.0016"}`: "0",
+		`spectator_feed_latency_seconds_bucket{product="ETH-USD",le="0.0032"}`: "2",
+		`spectator_feed_latency_seconds_bucket{product="ETH-USD",le="+Inf"}`:   "2",
+		`spectator_feed_latency_seconds_sum{product="ETH-USD"}`:                "0.004",
+		`spectator_feed_latency_seconds_count{product="ETH-USD"}`:              "2",
+		`spectator_apply_latency_seconds_count{product="ETH-USD"}`:             "2",
+		`spectator_render_seconds_bucket{le="0.0016"}`:                         "0",
+		`spectator_render_seconds_bucket{le="0.0032"}`:                         "1",
+		`spectator_render_seconds_sum`:                                         "0.003",
+		`spectator_render_seconds_count`:                                       "1",
+	}
+
+	for name, want := range expected {
+		got, ok := samples[name]
+		if !ok {
+			t.Errorf("Missing sample %v", name)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("%v = %v, expected %v", name, got, want)
+		}
+	}
+
+	if _, ok := samples[`spectator_messages_total{product="ETH-USD",type="match"}`]; ok {
+		t.Error("Unexpected sample for a message type that was never seen")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultWebsocketURL = "wss://ws-feed.pro.coinbase.com"
+	DefaultRestURL      = "https://api.pro.coinbase.com"
+
+	SandboxWebsocketURL = "wss://ws-feed-public.sandbox.pro.coinbase.com"
+	SandboxRestURL      = "https://api-public.sandbox.pro.coinbase.com"
+)
+
+type Options struct {
+	Feed Feed
+	Mode Mode
+
+	WebsocketURL string
+	RestURL      string
+	HTTPClient   *http.Client
+	Dialer       *websocket.Dialer
+	Recorder     *Recorder
+	Credentials  Credentials
+
+	ReconnectMin time.Duration
+	ReconnectMax time.Duration
+	StaleTimeout time.Duration
+
+	SnapshotInterval time.Duration
+	RequestTimeout   time.Duration
+
+	ValidateInterval time.Duration
+	ResyncInvalid    bool
+
+	CheckpointInterval time.Duration
+	Checkpoints        CheckpointStore
+	MessageLog         MessageLog
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.WebsocketURL == "" {
+		opts.WebsocketURL = DefaultWebsocketURL
+	}
+
+	if opts.RestURL == "" {
+		opts.RestURL = DefaultRestURL
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+
+	if opts.ReconnectMin <= 0 {
+		opts.ReconnectMin = DefaultReconnectMin
+	}
+
+	if opts.ReconnectMax <= 0 {
+		opts.ReconnectMax = DefaultReconnectMax
+	}
+
+	if opts.ReconnectMax < opts.ReconnectMin {
+		opts.ReconnectMax = opts.ReconnectMin
+	}
+
+	if opts.StaleTimeout == 0 && opts.Feed == nil {
+		opts.StaleTimeout = DefaultStaleTimeout
+	}
+
+	if opts.SnapshotInterval == 0 && opts.Feed == nil {
+		opts.SnapshotInterval = DefaultSnapshotInterval
+	}
+
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = DefaultRequestTimeout
+	}
+
+	if opts.CheckpointInterval > 0 && opts.Checkpoints == nil {
+		opts.Checkpoints = NewMemoryCheckpointStore(DefaultCheckpointRetention,
+			DefaultCheckpointLimit)
+	}
+
+	if opts.CheckpointInterval > 0 && opts.MessageLog == nil {
+		opts.MessageLog = NewMemoryMessageLog(DefaultCheckpointRetention,
+			DefaultMessageLogLimit)
+	}
+
+	return opts
+}
@@ -1,217 +1,317 @@
 package main
 
 import (
-	"github.com/emirpasic/gods/trees/redblacktree"
-	"github.com/gorilla/websocket"
 	"github.com/shopspring/decimal"
 
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"sync"
 	"time"
 )
 
-type Message struct {
-	Sequence      int64           `json:"sequence"`
-	Type          string          `json:"type"`
-	Side          string          `json:"side"`
-	Price         decimal.Decimal `json:"price"`
-	Size          decimal.Decimal `json:"size"`
-	OrderId       string          `json:"order_id"`
-	MakerOrderId  string          `json:"maker_order_id"`
-	RemainingSize decimal.Decimal `json:"remaining_size"`
-	NewSize       decimal.Decimal `json:"new_size"`
-	ProductId     string          `json:"product_id"`
-	Time          time.Time       `json:"time"`
-	Reason        string          `json:"reason"`
-	OrderType     string          `json:"order_type"`
-	ClientOid     string          `json:"client_oid"`
-}
-
-type LevelThree struct {
-	Sequence int64             `json:"sequence"`
-	Bids     []LevelThreeEntry `json:"bids"`
-	Asks     []LevelThreeEntry `json:"asks"`
-}
-
-type LevelThreeEntry []string
-
-type Sub struct {
-	Type       string   `json:"type"`
-	ProductIds []string `json:"product_ids"`
-	Channels   []string `json:"channels"`
-}
-
 type Entry struct {
-	Id    string
-	Side  string
-	Price decimal.Decimal
-	Size  decimal.Decimal
+	Id     string
+	Side   Side
+	Price  decimal.Decimal
+	Size   decimal.Decimal
+	Opened time.Time
+	Own    bool
 }
 
 type Entries map[string]Entry
 
+const maxBuffered = 1 << 16
+
 type OrderBook struct {
-	Msg <-chan Message
-	Err <-chan error
+	Err   <-chan error
+	State <-chan ConnState
 
-	asks *redblacktree.Tree
-	bids *redblacktree.Tree
+	asks   *priceIndex
+	bids   *priceIndex
+	orders map[string]*priceLevel
+	scale  int32
 
-	askLock sync.Mutex
-	bidLock sync.Mutex
+	bookLock sync.RWMutex
 
-	msg chan Message
-	err chan error
+	subsLock   sync.RWMutex
+	subs       map[*Subscription]struct{}
+	subsClosed bool
 
-	running  bool
-	coin     string
+	coin string
+	mode Mode
+
+	seqLock  sync.Mutex
 	sequence int64
+	seqTime  time.Time
 
-	conn *websocket.Conn
-}
+	checkpoints    CheckpointStore
+	log            MessageLog
+	interval       time.Duration
+	lastCheckpoint time.Time
 
-func NewOrderBook(coin string) (*OrderBook, error) {
-	var o OrderBook
-	var err error
+	syncing  bool
+	buffered []Message
+	retry    backoff
+
+	check      *FeedSnapshot
+	validating bool
+	checkLog   []Message
+	crossed    bool
+	corrupt    bool
+
+	pendingLock sync.Mutex
+	pending     map[string]PendingOrder
+
+	ownLock sync.Mutex
+	own     map[string]struct{}
+	fills   []Fill
+
+	stats bookStats
 
-	o.conn, _, err = websocket.DefaultDialer.Dial("wss://ws-feed.pro.coinbase.com", nil)
+	manager *BookManager
+}
+
+func NewOrderBook(ctx context.Context, coin string,
+	opts Options) (*OrderBook, error) {
+	m, err := NewBookManager(ctx, []string{coin}, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	o.asks = redblacktree.NewWith(DecimalComparator)
-	o.bids = redblacktree.NewWith(ReverseDecimalComparator)
+	return m.Book(coin), nil
+}
+
+func newOrderBook(coin string, m *BookManager) *OrderBook {
+	o := newOfflineBook(coin)
 
-	o.msg = make(chan Message, 2048)
-	o.Msg = o.msg
-	o.err = make(chan error, 0)
-	o.Err = o.err
+	o.Err = m.Err
+	o.State = m.State
+
+	o.mode = m.mode
+	o.manager = m
+
+	o.checkpoints = m.checkpoints
+	o.log = m.log
+	o.interval = m.interval
+
+	return o
+}
+
+func newOfflineBook(coin string) *OrderBook {
+	var o OrderBook
+
+	o.asks = newPriceIndex(Sell)
+	o.bids = newPriceIndex(Buy)
+	o.orders = make(map[string]*priceLevel)
+	o.scale = DefaultPriceScale
 
-	o.running = true
 	o.coin = coin
-	o.watchBook()
+	o.pending = make(map[string]PendingOrder)
+	o.subs = make(map[*Subscription]struct{})
+	o.own = make(map[string]struct{})
 
-	return &o, nil
+	return &o
 }
 
-func (o *OrderBook) Shutdown() {
-	if !o.running {
-		return
+func (o *OrderBook) Close() error {
+	if o.manager == nil {
+		return nil
 	}
-	o.running = false
 
-	o.conn.WriteMessage(websocket.CloseMessage,
-		websocket.FormatCloseMessage(websocket.
-			CloseNormalClosure, ""))
+	return o.manager.Close()
 }
 
-func (o *OrderBook) Entries(side string, count int) []Entries {
-	entries := make([]Entries, 0)
+func (o *OrderBook) Wait() error {
+	if o.manager == nil {
+		return nil
+	}
 
-	tree := o.tree(side)
-	lock := o.lock(side)
+	return o.manager.Wait()
+}
 
-	lock.Lock()
-	defer lock.Unlock()
+func (o *OrderBook) Product() string {
+	return o.coin
+}
 
-	it := tree.Iterator()
-	for i := 0; i < count; i++ {
-		copies := make(Entries, 0)
+func (o *OrderBook) Reconstructor() Reconstructor {
+	return Reconstructor{o.checkpoints, o.log}
+}
 
-		ok := it.Next()
-		if !ok {
-			break
-		}
+func (o *OrderBook) Sequence() int64 {
+	seq, _ := o.position()
+	return seq
+}
 
-		e := it.Value().(Entries)
-		for _, j := range e {
-			copies[j.Id] = j
-		}
+func (o *OrderBook) Time() time.Time {
+	_, t := o.position()
+	return t
+}
 
-		entries = append(entries, copies)
+func (o *OrderBook) Entries(side Side, count int) []Entries {
+	entries := make([]Entries, 0)
+
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	it := o.index(side).Iterator()
+	for i := 0; i < count && it.Next(); i++ {
+		entries = append(entries, it.Value().Entries())
 	}
 
 	return entries
 
 }
 
-func (o *OrderBook) watchBook() {
-	go func() {
-		defer func() {
-			close(o.err)
-			close(o.msg)
-			o.conn.Close()
-			o.running = false
-		}()
+func (o *OrderBook) handle(msg Message, snaps *snapshotQueue) {
+	if o.mode == ModeLevel2 {
+		o.applyLevelTwo(msg)
+		return
+	}
+
+	if msg.Type == TypeActivate && msg.Sequence == 0 {
+		o.activate(msg)
+		o.publish(msg)
+		return
+	}
+
+	if o.syncing {
+		if len(o.buffered) >= maxBuffered {
+			o.buffered = o.buffered[:0]
+		}
+
+		o.buffered = append(o.buffered, msg)
+		return
+	}
 
-		var msg Message
-		var err error
+	if !o.apply(msg) {
+		o.stats.gap()
+		o.resync(snaps)
+		return
+	}
 
-		sub := Sub{"subscribe", []string{o.coin}, []string{"full"}}
+	if o.validating && len(o.checkLog) < maxBuffered {
+		o.checkLog = append(o.checkLog, msg)
+	}
+
+	if o.corrupt {
+		o.corrupt = false
 
-		err = o.conn.WriteJSON(sub)
-		if err != nil {
-			o.sendError(err)
+		if o.manager.resyncInvalid {
+			o.resync(snaps)
 			return
 		}
+	}
+
+	if o.check != nil && o.Sequence() >= o.check.Sequence {
+		o.validate(*o.check, snaps)
+	}
+}
+
+func (o *OrderBook) resync(snaps *snapshotQueue) {
+	o.stats.resync()
+	o.sync(snaps)
+}
+
+func (o *OrderBook) sync(snaps *snapshotQueue) {
+	o.manager.setState(StateResyncing)
+	o.syncing = true
+	o.buffered = o.buffered[:0]
+	o.checkLog = o.checkLog[:0]
+
+	snaps.Sync(o.coin, 0)
+}
+
+func (o *OrderBook) apply(msg Message) bool {
+	seq, _ := o.position()
+
+	if msg.Sequence <= seq {
+		return true
+	}
+
+	if msg.Sequence != seq+1 {
+		return false
+	}
+
+	o.bookLock.Lock()
+	tick := o.messageTick(msg)
+	o.setPosition(msg.Sequence, msg.Time)
+	o.update(msg, tick)
+	err := o.checkIntegrity(msg, tick)
+	o.bookLock.Unlock()
+
+	if err != nil {
+		o.corrupt = true
+		o.sendError(err)
+	}
+
+	o.stats.observe(msg, time.Now())
+	o.record(msg)
+	o.publish(msg)
 
-		o.loadOrderBook()
-
-		for {
-			err := o.conn.ReadJSON(&msg)
-			if err != nil {
-				if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-					o.sendError(err)
-				}
-				break
-			}
-
-			if msg.Sequence <= o.sequence {
-				continue
-			}
-
-			if msg.Sequence != o.sequence+1 {
-				o.loadOrderBook()
-				continue
-			}
-
-			o.sequence = msg.Sequence
-
-			switch msg.Type {
-			case "received":
-			case "open":
-				o.open(msg)
-			case "done":
-				o.done(msg)
-			case "match":
-				o.match(msg)
-			case "change":
-				o.change(msg)
-			default:
-				o.sendError(errors.New("Unknown message type"))
-			}
-
-			o.msg <- msg
+	return true
+}
+
+func (o *OrderBook) update(msg Message, tick int64) {
+	switch msg.Type {
+	case TypeReceived:
+		o.received(msg)
+	case TypeOpen:
+		o.removePending(msg.OrderId)
+		o.open(msg, tick)
+	case TypeDone:
+		o.removePending(msg.OrderId)
+		o.done(msg, tick)
+	case TypeMatch:
+		o.fillPending(msg)
+		o.match(msg, tick)
+	case TypeChange:
+		if !o.changePending(msg) {
+			o.change(msg, tick)
 		}
-	}()
+	case TypeActivate:
+		o.activate(msg)
+	case TypeSnapshot, TypeL2Update:
+		o.sendError(fmt.Errorf("Unexpected %v message in full mode", msg.Type))
+	case TypeSubscriptions, TypeError:
+	default:
+		o.sendError(fmt.Errorf("Unknown message type: %v", msg.Type))
+	}
+}
+
+func (o *OrderBook) record(msg Message) {
+	if o.log != nil {
+		o.log.Append(msg)
+	}
+
+	if o.checkpoints == nil || o.interval <= 0 {
+		return
+	}
+
+	if !o.lastCheckpoint.IsZero() && msg.Time.Sub(o.lastCheckpoint) < o.interval {
+		return
+	}
+
+	o.lastCheckpoint = msg.Time
+
+	err := o.checkpoints.Save(o.Checkpoint())
+	if err != nil {
+		o.sendError(err)
+	}
 }
 
-func (o *OrderBook) open(msg Message) {
+func (o *OrderBook) open(msg Message, tick int64) {
 	var e Entry
 
 	e.Id = msg.OrderId
 	e.Side = msg.Side
 	e.Price = msg.Price
 	e.Size = msg.RemainingSize
+	e.Opened = msg.Time
 
-	o.setEntry(e)
+	o.setEntry(e, tick)
 }
 
-func (o *OrderBook) done(msg Message) {
+func (o *OrderBook) done(msg Message, tick int64) {
 	if msg.Price.Equal(decimal.Zero) {
 		return
 	}
@@ -222,228 +322,172 @@ func (o *OrderBook) done(msg Message) {
 	e.Side = msg.Side
 	e.Price = msg.Price
 
-	o.removeEntry(e)
+	o.removeEntry(e, tick)
 }
 
-func (o *OrderBook) match(msg Message) {
-	e, ok := o.entry(msg.Side, msg.Price, msg.MakerOrderId)
+func (o *OrderBook) match(msg Message, tick int64) {
+	e, ok := o.entry(msg.Side, tick, msg.MakerOrderId)
 	if !ok {
 		return
 	}
 
 	e.Size = e.Size.Sub(msg.Size)
-	o.setEntry(e)
+	o.setEntry(e, tick)
 }
 
-func (o *OrderBook) change(msg Message) {
-	var e Entry
+func (o *OrderBook) change(msg Message, tick int64) {
+	e, ok := o.entry(msg.Side, tick, msg.OrderId)
+	if !ok {
+		return
+	}
 
-	e.Id = msg.OrderId
-	e.Side = msg.Side
-	e.Price = msg.Price
 	e.Size = msg.NewSize
-
-	o.setEntry(e)
+	o.setEntry(e, tick)
 }
 
-func (o *OrderBook) loadOrderBook() {
-	o.askLock.Lock()
-	o.bidLock.Lock()
-	o.bids.Clear()
-	o.asks.Clear()
-	o.askLock.Unlock()
-	o.bidLock.Unlock()
+func (o *OrderBook) loadOrderBook(snap FeedSnapshot) bool {
+	o.bookLock.Lock()
+	o.clear()
+	o.setIncrement(snap.Increment)
 
-	resp, err := http.Get(fmt.Sprintf("https://api.pro.coinbase.com/products/%v/book?level=3", coin))
-	if err != nil {
-		o.sendError(err)
-		o.Shutdown()
-		return
+	for _, e := range snap.Bids {
+		o.setEntry(e, o.tick(e.Price))
 	}
-	defer resp.Body.Close()
 
-	buf, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		o.sendError(err)
-		o.Shutdown()
-		return
+	for _, e := range snap.Asks {
+		o.setEntry(e, o.tick(e.Price))
 	}
 
-	var parsed LevelThree
-
-	err = json.Unmarshal(buf, &parsed)
-	if err != nil {
-		o.sendError(err)
-		o.Shutdown()
-		return
-	}
+	o.setPosition(snap.Sequence, time.Time{})
+	o.bookLock.Unlock()
 
-	for _, b := range parsed.Bids {
-		var e Entry
+	o.clearPending()
+	o.syncing = false
+	o.crossed = false
+	o.corrupt = false
+	o.lastCheckpoint = time.Time{}
 
-		e.Price, err = decimal.NewFromString(b[0])
-		if err != nil {
-			o.sendError(err)
-			o.Shutdown()
-			return
+	for _, msg := range o.buffered {
+		if !o.apply(msg) {
+			return false
 		}
-		e.Size, err = decimal.NewFromString(b[1])
-		if err != nil {
-			o.sendError(err)
-			o.Shutdown()
-			return
-		}
-		e.Id = b[2]
-		e.Side = "buy"
-
-		o.setEntry(e)
 	}
 
-	for _, a := range parsed.Asks {
-		var e Entry
-
-		e.Price, err = decimal.NewFromString(a[0])
-		if err != nil {
-			o.sendError(err)
-			o.Shutdown()
-			return
-		}
-		e.Size, err = decimal.NewFromString(a[1])
-		if err != nil {
-			o.sendError(err)
-			o.Shutdown()
-			return
-		}
-		e.Id = a[2]
-		e.Side = "sell"
-
-		o.setEntry(e)
-	}
+	o.buffered = o.buffered[:0]
 
-	o.sequence = parsed.Sequence
+	return true
 }
 
-func (o *OrderBook) lock(side string) *sync.Mutex {
-	switch side {
-	case "sell":
-		return &o.askLock
-	case "buy":
-		return &o.bidLock
-	}
-
-	return nil
+func (o *OrderBook) clear() {
+	o.bids.Clear()
+	o.asks.Clear()
+	o.orders = make(map[string]*priceLevel)
 }
 
-func (o *OrderBook) tree(side string) *redblacktree.Tree {
-	switch side {
-	case "sell":
+func (o *OrderBook) index(side Side) *priceIndex {
+	if side == Sell {
 		return o.asks
-	case "buy":
-		return o.bids
 	}
 
-	return nil
+	return o.bids
 }
 
-func (o *OrderBook) entries(side string, key decimal.Decimal) (Entries, bool) {
-	tree := o.tree(side)
-	lock := o.lock(side)
-
-	lock.Lock()
-	defer lock.Unlock()
-
-	values, ok := tree.Get(key)
+func (o *OrderBook) tick(price decimal.Decimal) int64 {
+	return toTicks(price, o.scale)
+}
 
-	if !ok {
-		return nil, false
+func (o *OrderBook) messageTick(msg Message) int64 {
+	switch msg.Type {
+	case TypeOpen:
+		return o.tick(msg.Price)
+	case TypeDone, TypeChange:
+		return o.orderTick(msg.OrderId)
+	case TypeMatch:
+		return o.orderTick(msg.MakerOrderId)
 	}
 
-	entries := make(Entries)
-	for k, v := range values.(Entries) {
-		entries[k] = v
+	return 0
+}
+
+func (o *OrderBook) orderTick(id string) int64 {
+	if l, ok := o.orders[id]; ok {
+		return l.tick
 	}
 
-	return entries, true
+	return 0
 }
 
-func (o *OrderBook) updateEntries(side string, price decimal.Decimal, e Entries) {
-	tree := o.tree(side)
-	lock := o.lock(side)
-
-	lock.Lock()
-	defer lock.Unlock()
+func (o *OrderBook) setIncrement(inc decimal.Decimal) {
+	scale := int32(DefaultPriceScale)
 
-	if len(e) == 0 {
-		tree.Remove(price)
-	} else {
-		tree.Put(price, e)
+	if inc.GreaterThan(decimal.Zero) {
+		scale = 0
+		for scale < maxPriceScale &&
+			!inc.Shift(scale).Equal(inc.Shift(scale).Truncate(0)) {
+			scale++
+		}
 	}
+
+	o.scale = scale
 }
 
-func (o *OrderBook) entry(side string, price decimal.Decimal,
-	id string) (Entry, bool) {
-	var entry Entry
+func (o *OrderBook) level(side Side, tick int64) (*priceLevel, bool) {
+	return o.index(side).Get(tick)
+}
 
-	entries, ok := o.entries(side, price)
+func (o *OrderBook) entry(side Side, tick int64, id string) (Entry, bool) {
+	l, ok := o.level(side, tick)
 	if !ok {
-		return entry, false
+		return Entry{}, false
 	}
 
-	entry, ok = entries[id]
-	return entry, ok
+	return l.Get(id)
 }
 
-func (o *OrderBook) setEntry(e Entry) {
-	entries, ok := o.entries(e.Side, e.Price)
+func (o *OrderBook) setEntry(e Entry, tick int64) {
+	l, ok := o.level(e.Side, tick)
 	if !ok {
-		entries = Entries{}
+		l = newPriceLevel(e.Price, tick)
+		o.index(e.Side).Put(tick, l)
 	}
 
-	entries[e.Id] = e
-	o.updateEntries(e.Side, e.Price, entries)
+	e.Own = o.IsOwn(e.Id)
+
+	l.Set(e)
+	o.orders[e.Id] = l
 }
 
-func (o *OrderBook) removeEntry(e Entry) {
-	entries, ok := o.entries(e.Side, e.Price)
+func (o *OrderBook) removeEntry(e Entry, tick int64) {
+	l, ok := o.level(e.Side, tick)
 	if !ok {
 		return
 	}
 
-	delete(entries, e.Id)
-	o.updateEntries(e.Side, e.Price, entries)
+	l.Remove(e.Id)
+	delete(o.orders, e.Id)
+
+	if l.Empty() {
+		o.index(e.Side).Remove(tick)
+	}
 }
 
-func DecimalComparator(a, b interface{}) int {
-	aAsserted := a.(decimal.Decimal)
-	bAsserted := b.(decimal.Decimal)
+func (o *OrderBook) position() (int64, time.Time) {
+	o.seqLock.Lock()
+	defer o.seqLock.Unlock()
 
-	switch {
-	case aAsserted.GreaterThan(bAsserted):
-		return 1
-	case aAsserted.LessThan(bAsserted):
-		return -1
-	default:
-		return 0
-	}
+	return o.sequence, o.seqTime
 }
 
-func ReverseDecimalComparator(a, b interface{}) int {
-	aAsserted := a.(decimal.Decimal)
-	bAsserted := b.(decimal.Decimal)
+func (o *OrderBook) setPosition(seq int64, t time.Time) {
+	o.seqLock.Lock()
+	defer o.seqLock.Unlock()
 
-	switch {
-	case aAsserted.GreaterThan(bAsserted):
-		return -1
-	case aAsserted.LessThan(bAsserted):
-		return 1
-	default:
-		return 0
-	}
+	o.sequence = seq
+	o.seqTime = t
 }
 
 func (o *OrderBook) sendError(err error) {
-	select {
-	case o.err <- err:
-	default:
+	if o.manager != nil {
+		o.manager.sendError(err)
 	}
 }
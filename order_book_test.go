@@ -0,0 +1,150 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+	"testing"
+)
+
+const (
+	benchLevels = 500
+	benchOrders = 20
+	benchRing   = 1 << 14
+)
+
+var benchTick = decimal.New(1, -2)
+
+func benchPrice(side Side, level int) decimal.Decimal {
+	offset := benchTick.Mul(decimal.NewFromInt(int64(level + 1)))
+
+	if side == Buy {
+		return decimal.NewFromInt(2000).Sub(offset)
+	}
+
+	return decimal.NewFromInt(2000).Add(offset)
+}
+
+func benchSnapshot() FeedSnapshot {
+	snap := FeedSnapshot{Sequence: 1}
+	size := decimal.NewFromInt(1000)
+
+	for _, side := range []Side{Buy, Sell} {
+		for l := 0; l < benchLevels; l++ {
+			price := benchPrice(side, l)
+
+			for n := 0; n < benchOrders; n++ {
+				e := Entry{Id: fmt.Sprintf("%v-%d-%d", side, l, n), Side: side,
+					Price: price, Size: size}
+
+				if side == Buy {
+					snap.Bids = append(snap.Bids, e)
+				} else {
+					snap.Asks = append(snap.Asks, e)
+				}
+			}
+		}
+	}
+
+	return snap
+}
+
+func benchResting(i int) (Side, decimal.Decimal, string) {
+	side := Buy
+	if i%2 == 1 {
+		side = Sell
+	}
+
+	l := (i / 2) % benchLevels
+	n := (i / 2 / benchLevels) % benchOrders
+
+	return side, benchPrice(side, l), fmt.Sprintf("%v-%d-%d", side, l, n)
+}
+
+func benchApply(b *testing.B, msgs []Message, reload bool) {
+	snap := benchSnapshot()
+
+	o := newOfflineBook("ETH-USD")
+	o.loadOrderBook(snap)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	seq := snap.Sequence
+	for i := 0; i < b.N; i++ {
+		if reload && i > 0 && i%len(msgs) == 0 {
+			b.StopTimer()
+			o.loadOrderBook(snap)
+			seq = snap.Sequence
+			b.StartTimer()
+		}
+
+		msg := msgs[i%len(msgs)]
+		seq++
+		msg.Sequence = seq
+
+		if !o.apply(msg) {
+			b.Fatalf("Failed to apply %v", msg.Sequence)
+		}
+	}
+}
+
+func BenchmarkApplyOpen(b *testing.B) {
+	msgs := make([]Message, benchRing)
+	for i := range msgs {
+		side, price, _ := benchResting(i)
+
+		msgs[i] = Message{Type: TypeOpen, ProductId: "ETH-USD", Side: side, Price: price,
+			OrderId: fmt.Sprintf("open-%d", i), RemainingSize: decimal.NewFromInt(1)}
+	}
+
+	benchApply(b, msgs, true)
+}
+
+func BenchmarkApplyDone(b *testing.B) {
+	msgs := make([]Message, benchRing)
+	for i := range msgs {
+		side, price, id := benchResting(i)
+
+		msgs[i] = Message{Type: TypeDone, ProductId: "ETH-USD", Side: side, Price: price,
+			OrderId: id, Reason: "canceled"}
+	}
+
+	benchApply(b, msgs, true)
+}
+
+func BenchmarkApplyMatch(b *testing.B) {
+	msgs := make([]Message, benchRing)
+	for i := range msgs {
+		side, price, id := benchResting(i)
+
+		msgs[i] = Message{Type: TypeMatch, ProductId: "ETH-USD", Side: side, Price: price,
+			MakerOrderId: id, TakerOrderId: "taker", Size: decimal.New(1, -3)}
+	}
+
+	benchApply(b, msgs, false)
+}
+
+func BenchmarkApplyChange(b *testing.B) {
+	msgs := make([]Message, benchRing)
+	for i := range msgs {
+		side, price, id := benchResting(i)
+
+		msgs[i] = Message{Type: TypeChange, ProductId: "ETH-USD", Side: side, Price: price,
+			OrderId: id, NewSize: decimal.NewFromInt(int64(500 + i%2))}
+	}
+
+	benchApply(b, msgs, false)
+}
+
+func BenchmarkSnapshot(b *testing.B) {
+	o := newOfflineBook("ETH-USD")
+	o.loadOrderBook(benchSnapshot())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		o.Snapshot(50)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"time"
+)
+
+type OrderStatus struct {
+	Entry
+	Position  int
+	SizeAhead decimal.Decimal
+	Age       time.Duration
+}
+
+func (o *OrderBook) Order(id string) (OrderStatus, bool) {
+	var s OrderStatus
+
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	l, ok := o.orders[id]
+	if !ok {
+		return s, false
+	}
+
+	s.Entry, _ = l.Get(id)
+	s.Position, s.SizeAhead, _ = l.Position(id)
+
+	if !s.Opened.IsZero() {
+		_, now := o.position()
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		s.Age = now.Sub(s.Opened)
+	}
+
+	return s, true
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"sort"
+	"time"
+)
+
+type Liquidity string
+
+const (
+	LiquidityMaker Liquidity = "maker"
+	LiquidityTaker Liquidity = "taker"
+)
+
+const maxFills = 1024
+
+type Fill struct {
+	TradeId   int64
+	OrderId   string
+	Side      Side
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	Fee       decimal.Decimal
+	Liquidity Liquidity
+	Time      time.Time
+}
+
+func (o *OrderBook) IsOwn(id string) bool {
+	o.ownLock.Lock()
+	defer o.ownLock.Unlock()
+
+	_, ok := o.own[id]
+	return ok
+}
+
+func (o *OrderBook) OwnOrders() []Entry {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	o.ownLock.Lock()
+	defer o.ownLock.Unlock()
+
+	orders := make([]Entry, 0, len(o.own))
+	for id := range o.own {
+		l, ok := o.orders[id]
+		if !ok {
+			continue
+		}
+
+		e, _ := l.Get(id)
+		orders = append(orders, e)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Opened.Before(orders[j].Opened)
+	})
+
+	return orders
+}
+
+func (o *OrderBook) Fills(limit int) []Fill {
+	o.ownLock.Lock()
+	defer o.ownLock.Unlock()
+
+	start := 0
+	if limit > 0 && len(o.fills) > limit {
+		start = len(o.fills) - limit
+	}
+
+	return append([]Fill{}, o.fills[start:]...)
+}
+
+func (o *OrderBook) handleOwn(msg Message) {
+	switch msg.Type {
+	case TypeReceived, TypeOpen:
+		o.setOwn(msg.OrderId, true)
+	case TypeDone:
+		o.setOwn(msg.OrderId, false)
+	case TypeMatch:
+		o.addFill(msg)
+	}
+}
+
+func (o *OrderBook) setOwn(id string, own bool) {
+	if id == "" {
+		return
+	}
+
+	o.ownLock.Lock()
+	if own {
+		o.own[id] = struct{}{}
+	} else {
+		delete(o.own, id)
+	}
+	o.ownLock.Unlock()
+
+	if !own {
+		return
+	}
+
+	o.bookLock.Lock()
+	defer o.bookLock.Unlock()
+
+	l, ok := o.orders[id]
+	if !ok {
+		return
+	}
+
+	e, _ := l.Get(id)
+	if !e.Own {
+		e.Own = true
+		l.Set(e)
+	}
+}
+
+func (o *OrderBook) addFill(msg Message) {
+	var f Fill
+
+	f.TradeId = msg.TradeId
+	f.Price = msg.Price
+	f.Size = msg.Size
+	f.Time = msg.Time
+
+	switch {
+	case o.IsOwn(msg.MakerOrderId):
+		f.Liquidity = LiquidityMaker
+	case o.IsOwn(msg.TakerOrderId):
+		f.Liquidity = LiquidityTaker
+	case !msg.TakerFeeRate.IsZero():
+		f.Liquidity = LiquidityTaker
+	default:
+		f.Liquidity = LiquidityMaker
+	}
+
+	if f.Liquidity == LiquidityMaker {
+		f.OrderId = msg.MakerOrderId
+		f.Side = msg.Side
+	} else {
+		f.OrderId = msg.TakerOrderId
+		f.Side = msg.Side.Opposite()
+		f.Fee = msg.Price.Mul(msg.Size).Mul(msg.TakerFeeRate)
+	}
+
+	o.ownLock.Lock()
+	defer o.ownLock.Unlock()
+
+	for i := len(o.fills) - 1; i >= 0; i-- {
+		if o.fills[i].TradeId == f.TradeId && o.fills[i].OrderId == f.OrderId {
+			return
+		}
+	}
+
+	if len(o.fills) >= maxFills {
+		o.fills = o.fills[1:]
+	}
+
+	o.fills = append(o.fills, f)
+}
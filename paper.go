@@ -0,0 +1,436 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	PaperOpen     = PaperStatus("open")
+	PaperFilled   = PaperStatus("filled")
+	PaperCanceled = PaperStatus("canceled")
+)
+
+var (
+	DefaultMakerFee = decimal.New(5, -3)
+	DefaultTakerFee = decimal.New(5, -3)
+)
+
+type PaperStatus string
+
+type PaperOrder struct {
+	Id        string
+	Side      Side
+	OrderType OrderType
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	Filled    decimal.Decimal
+	Ahead     decimal.Decimal
+	Status    PaperStatus
+	Created   time.Time
+
+	sequence int64
+	queue    map[string]decimal.Decimal
+}
+
+type PaperFill struct {
+	OrderId   string
+	Side      Side
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	Fee       decimal.Decimal
+	Liquidity Liquidity
+	Time      time.Time
+}
+
+type Position struct {
+	Size       decimal.Decimal
+	AvgPrice   decimal.Decimal
+	Realized   decimal.Decimal
+	Unrealized decimal.Decimal
+	Fees       decimal.Decimal
+	PnL        decimal.Decimal
+}
+
+type PaperTrader struct {
+	book *OrderBook
+	sub  *Subscription
+
+	makerFee decimal.Decimal
+	takerFee decimal.Decimal
+
+	lock     sync.Mutex
+	next     int64
+	orders   map[string]*PaperOrder
+	fills    []PaperFill
+	size     decimal.Decimal
+	avgPrice decimal.Decimal
+	realized decimal.Decimal
+	fees     decimal.Decimal
+
+	done chan struct{}
+}
+
+func NewPaperTrader(book *OrderBook, makerFee, takerFee decimal.Decimal) *PaperTrader {
+	var p PaperTrader
+
+	p.book = book
+	p.makerFee = makerFee
+	p.takerFee = takerFee
+	p.orders = make(map[string]*PaperOrder)
+
+	p.sub = book.Subscribe(DefaultSubscriptionBuffer, PolicyBlock)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		for msg := range p.sub.C {
+			p.Observe(msg)
+		}
+	}()
+
+	return &p
+}
+
+func (p *PaperTrader) Close() {
+	p.sub.Close()
+	<-p.done
+}
+
+func (p *PaperTrader) Limit(side Side, price, size decimal.Decimal) (PaperOrder, error) {
+	if !price.IsPositive() || !size.IsPositive() {
+		return PaperOrder{}, fmt.Errorf("Invalid limit order: %v @ %v", size, price)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	o := p.newOrder(side, OrderLimit, size)
+	o.Price = price
+
+	fills, seq, queue, ahead := p.book.paperLimit(side, price, size)
+	o.sequence = seq
+
+	p.take(o, fills)
+
+	if o.Filled.LessThan(o.Size) {
+		o.queue = queue
+		o.Ahead = ahead
+		p.orders[o.Id] = o
+	} else {
+		o.Status = PaperFilled
+	}
+
+	return *o, nil
+}
+
+func (p *PaperTrader) Market(side Side, size decimal.Decimal) (PaperOrder, error) {
+	if !size.IsPositive() {
+		return PaperOrder{}, fmt.Errorf("Invalid market order: %v", size)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	o := p.newOrder(side, OrderMarket, size)
+
+	fills, _ := p.book.paperMarket(side, size)
+	p.take(o, fills)
+
+	o.Status = PaperFilled
+	if o.Filled.LessThan(o.Size) {
+		o.Status = PaperCanceled
+	}
+
+	return *o, nil
+}
+
+func (p *PaperTrader) Cancel(id string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	o, ok := p.orders[id]
+	if !ok {
+		return fmt.Errorf("Unknown paper order: %v", id)
+	}
+
+	o.Status = PaperCanceled
+	delete(p.orders, id)
+
+	return nil
+}
+
+func (p *PaperTrader) CancelAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, o := range p.orders {
+		o.Status = PaperCanceled
+		delete(p.orders, id)
+	}
+}
+
+func (p *PaperTrader) Orders() []PaperOrder {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	orders := make([]PaperOrder, 0, len(p.orders))
+	for _, o := range p.orders {
+		orders = append(orders, *o)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Created.Before(orders[j].Created)
+	})
+
+	return orders
+}
+
+func (p *PaperTrader) Fills() []PaperFill {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]PaperFill{}, p.fills...)
+}
+
+func (p *PaperTrader) Position() Position {
+	mark := p.book.Snapshot(1).Mid()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var pos Position
+
+	pos.Size = p.size
+	pos.AvgPrice = p.avgPrice
+	pos.Realized = p.realized
+	pos.Fees = p.fees
+
+	if !p.size.IsZero() && mark.IsPositive() {
+		pos.Unrealized = mark.Sub(p.avgPrice).Mul(p.size)
+	}
+
+	pos.PnL = pos.Realized.Add(pos.Unrealized).Sub(pos.Fees)
+
+	return pos
+}
+
+func (p *PaperTrader) Observe(msg Message) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, o := range p.orders {
+		if msg.Sequence != 0 && msg.Sequence <= o.sequence {
+			continue
+		}
+
+		if msg.Side != o.Side {
+			continue
+		}
+
+		switch msg.Type {
+		case TypeMatch:
+			p.observeMatch(o, msg)
+		case TypeDone:
+			p.dequeue(o, msg.OrderId, decimal.Zero)
+		case TypeChange:
+			if _, ok := o.queue[msg.OrderId]; ok {
+				p.dequeue(o, msg.OrderId, msg.NewSize)
+			}
+		}
+
+		if !o.Filled.LessThan(o.Size) {
+			o.Status = PaperFilled
+			delete(p.orders, id)
+		}
+	}
+}
+
+func (p *PaperTrader) observeMatch(o *PaperOrder, msg Message) {
+	if worse(o.Side, msg.Price, o.Price) {
+		p.fill(o, o.Price, o.Size.Sub(o.Filled), LiquidityMaker, msg.Time)
+		return
+	}
+
+	if !msg.Price.Equal(o.Price) {
+		return
+	}
+
+	size := msg.Size
+
+	if queued, ok := o.queue[msg.MakerOrderId]; ok {
+		consumed := decimal.Min(queued, size)
+		o.queue[msg.MakerOrderId] = queued.Sub(consumed)
+		o.Ahead = o.Ahead.Sub(consumed)
+		return
+	}
+
+	if len(o.queue) > 0 {
+		o.queue = nil
+		o.Ahead = decimal.Zero
+	}
+
+	if o.Ahead.IsPositive() {
+		consumed := decimal.Min(o.Ahead, size)
+		o.Ahead = o.Ahead.Sub(consumed)
+		size = size.Sub(consumed)
+	}
+
+	size = decimal.Min(size, o.Size.Sub(o.Filled))
+	if size.IsPositive() {
+		p.fill(o, o.Price, size, LiquidityMaker, msg.Time)
+	}
+}
+
+func (p *PaperTrader) dequeue(o *PaperOrder, id string, size decimal.Decimal) {
+	queued, ok := o.queue[id]
+	if !ok {
+		return
+	}
+
+	if size.GreaterThan(queued) {
+		size = queued
+	}
+
+	o.Ahead = o.Ahead.Sub(queued.Sub(size))
+
+	if size.IsZero() {
+		delete(o.queue, id)
+	} else {
+		o.queue[id] = size
+	}
+}
+
+func (p *PaperTrader) newOrder(side Side, t OrderType, size decimal.Decimal) *PaperOrder {
+	p.next++
+
+	return &PaperOrder{
+		Id:        fmt.Sprintf("paper-%d", p.next),
+		Side:      side,
+		OrderType: t,
+		Size:      size,
+		Status:    PaperOpen,
+		Created:   time.Now(),
+	}
+}
+
+func (p *PaperTrader) take(o *PaperOrder, fills []Level) {
+	for _, l := range fills {
+		p.fill(o, l.Price, l.Size, LiquidityTaker, time.Now())
+	}
+}
+
+func (p *PaperTrader) fill(o *PaperOrder, price, size decimal.Decimal,
+	liquidity Liquidity, t time.Time) {
+	rate := p.makerFee
+	if liquidity == LiquidityTaker {
+		rate = p.takerFee
+	}
+
+	f := PaperFill{
+		OrderId:   o.Id,
+		Side:      o.Side,
+		Price:     price,
+		Size:      size,
+		Fee:       price.Mul(size).Mul(rate),
+		Liquidity: liquidity,
+		Time:      t,
+	}
+
+	o.Filled = o.Filled.Add(size)
+
+	p.fills = append(p.fills, f)
+	p.fees = p.fees.Add(f.Fee)
+
+	p.apply(f)
+}
+
+func (p *PaperTrader) apply(f PaperFill) {
+	qty := f.Size
+	if f.Side == Sell {
+		qty = qty.Neg()
+	}
+
+	if p.size.IsZero() || p.size.Sign() == qty.Sign() {
+		total := p.size.Abs().Add(f.Size)
+		p.avgPrice = p.avgPrice.Mul(p.size.Abs()).Add(f.Price.Mul(f.Size)).Div(total)
+		p.size = p.size.Add(qty)
+		return
+	}
+
+	closing := decimal.Min(f.Size, p.size.Abs())
+
+	pnl := f.Price.Sub(p.avgPrice).Mul(closing)
+	if p.size.IsNegative() {
+		pnl = pnl.Neg()
+	}
+
+	p.realized = p.realized.Add(pnl)
+	p.size = p.size.Add(qty)
+
+	switch {
+	case p.size.IsZero():
+		p.avgPrice = decimal.Zero
+	case p.size.Sign() == qty.Sign():
+		p.avgPrice = f.Price
+	}
+}
+
+func (o *OrderBook) paperLimit(side Side, price, size decimal.Decimal) ([]Level,
+	int64, map[string]decimal.Decimal, decimal.Decimal) {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	fills, remaining := o.sweep(side, price, true, size)
+	seq, _ := o.position()
+
+	queue := make(map[string]decimal.Decimal)
+	var ahead decimal.Decimal
+
+	if !remaining.IsPositive() {
+		return fills, seq, queue, ahead
+	}
+
+	l, ok := o.level(side, o.tick(price))
+	if !ok {
+		return fills, seq, queue, ahead
+	}
+
+	for _, e := range l.Orders() {
+		queue[e.Id] = e.Size
+	}
+
+	return fills, seq, queue, l.size
+}
+
+func (o *OrderBook) paperMarket(side Side, size decimal.Decimal) ([]Level, decimal.Decimal) {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	return o.sweep(side, decimal.Zero, false, size)
+}
+
+func (o *OrderBook) sweep(side Side, limit decimal.Decimal, limited bool,
+	size decimal.Decimal) ([]Level, decimal.Decimal) {
+	var fills []Level
+
+	it := o.index(side.Opposite()).Iterator()
+	for size.IsPositive() && it.Next() {
+		l := it.Value()
+		if limited && worse(side.Opposite(), l.price, limit) {
+			break
+		}
+
+		take := decimal.Min(size, l.size)
+		size = size.Sub(take)
+
+		fills = append(fills, Level{Price: l.price, Size: take, Count: 1})
+	}
+
+	return fills, size
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"testing"
+)
+
+func paperMatch(side Side, price, size, maker string) Message {
+	return Message{Type: TypeMatch, Side: side, Price: decimal.RequireFromString(price),
+		Size: decimal.RequireFromString(size), MakerOrderId: maker, TakerOrderId: "taker"}
+}
+
+func paperOrder(p *PaperTrader, id string) (PaperOrder, bool) {
+	for _, o := range p.Orders() {
+		if o.Id == id {
+			return o, true
+		}
+	}
+
+	return PaperOrder{}, false
+}
+
+func paperFilled(p *PaperTrader, id string) decimal.Decimal {
+	var filled decimal.Decimal
+
+	for _, f := range p.Fills() {
+		if f.OrderId == id {
+			filled = filled.Add(f.Size)
+		}
+	}
+
+	return filled
+}
+
+func TestPaperLimitQueue(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  string
+		size   string
+		msgs   []Message
+		ahead  string
+		filled string
+		open   bool
+	}{
+		{"queued", "99", "2", nil, "1", "0", true},
+		{"ahead consumed", "99", "2", []Message{
+			paperMatch(Buy, "99", "0.4", "b1"),
+		}, "0.6", "0", true},
+		{"ahead cleared then filled", "99", "2", []Message{
+			paperMatch(Buy, "99", "1", "b1"),
+			paperMatch(Buy, "99", "1.5", "later"),
+		}, "0", "1.5", true},
+		{"unknown maker clears queue", "98", "1", []Message{
+			paperMatch(Buy, "98", "0.5", "later"),
+		}, "0", "0.5", true},
+		{"queued order canceled", "98", "1", []Message{
+			{Type: TypeDone, Side: Buy, OrderId: "b2", Price: decimal.NewFromInt(98)},
+		}, "0", "0", true},
+		{"queued order changed", "98", "1", []Message{
+			{Type: TypeChange, Side: Buy, OrderId: "b2", Price: decimal.NewFromInt(98),
+				NewSize: decimal.RequireFromString("0.5")},
+		}, "0.5", "0", true},
+		{"changed order then filled", "98", "1", []Message{
+			{Type: TypeChange, Side: Buy, OrderId: "b2", Price: decimal.NewFromInt(98),
+				NewSize: decimal.RequireFromString("0.5")},
+			paperMatch(Buy, "98", "0.5", "b2"),
+			paperMatch(Buy, "98", "0.3", "later"),
+		}, "0", "0.3", true},
+		{"other side ignored", "98", "1", []Message{
+			paperMatch(Sell, "98", "5", "a1"),
+		}, "2", "0", true},
+		{"traded through", "99", "2", []Message{
+			paperMatch(Buy, "98.5", "0.1", "elsewhere"),
+		}, "", "2", false},
+		{"crosses the spread", "101.5", "4", nil, "0", "3", true},
+	}
+
+	for _, tt := range tests {
+		p := NewPaperTrader(depthBook(), decimal.Zero, decimal.Zero)
+
+		o, err := p.Limit(Buy, decimal.RequireFromString(tt.price),
+			decimal.RequireFromString(tt.size))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, msg := range tt.msgs {
+			msg.Sequence = int64(2 + i)
+			p.Observe(msg)
+		}
+
+		if filled := paperFilled(p, o.Id); filled.String() != tt.filled {
+			t.Errorf("%v: expected %v filled, got %v", tt.name, tt.filled, filled)
+		}
+
+		cur, open := paperOrder(p, o.Id)
+		if open != tt.open {
+			t.Errorf("%v: expected open %v, got %v", tt.name, tt.open, open)
+		}
+
+		if open && cur.Ahead.String() != tt.ahead {
+			t.Errorf("%v: expected %v ahead, got %v", tt.name, tt.ahead, cur.Ahead)
+		}
+
+		p.Close()
+	}
+}
+
+func TestPaperPosition(t *testing.T) {
+	type order struct {
+		side  Side
+		limit string
+		size  string
+	}
+
+	tests := []struct {
+		name     string
+		orders   []order
+		size     string
+		avg      string
+		realized string
+		fees     string
+		pnl      string
+	}{
+		{"long", []order{{Buy, "", "2"}}, "2", "101", "0", "2.02", "-3.02"},
+		{"averaged", []order{{Buy, "", "2"}, {Buy, "100", "2"}},
+			"4", "100.5", "0", "2.02", "-2.02"},
+		{"closed", []order{{Buy, "", "2"}, {Sell, "", "2"}},
+			"0", "0", "-2", "4.02", "-6.02"},
+		{"long flipped short", []order{{Buy, "", "2"}, {Sell, "", "5"}},
+			"-3", "100", "-2", "7.02", "-10.52"},
+		{"short covered", []order{{Buy, "", "2"}, {Sell, "", "5"}, {Buy, "", "1"}},
+			"-2", "100", "-3", "8.03", "-12.03"},
+	}
+
+	for _, tt := range tests {
+		book := newOfflineBook("ETH-USD")
+		book.loadOrderBook(FeedSnapshot{Sequence: 1,
+			Bids: []Entry{{Id: "b", Side: Buy, Price: decimal.NewFromInt(100),
+				Size: decimal.NewFromInt(10)}},
+			Asks: []Entry{{Id: "a", Side: Sell, Price: decimal.NewFromInt(101),
+				Size: decimal.NewFromInt(10)}},
+		})
+
+		p := NewPaperTrader(book, decimal.Zero, decimal.New(1, -2))
+
+		for i, o := range tt.orders {
+			size := decimal.RequireFromString(o.size)
+
+			if o.limit == "" {
+				if _, err := p.Market(o.side, size); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+
+			po, err := p.Limit(o.side, decimal.RequireFromString(o.limit), size)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p.Observe(Message{Type: TypeMatch, Sequence: int64(2 + i), Side: o.side,
+				Price: po.Price, Size: size, MakerOrderId: "later"})
+		}
+
+		pos := p.Position()
+
+		for _, v := range []struct {
+			field     string
+			got, want string
+		}{
+			{"size", pos.Size.String(), tt.size},
+			{"average price", pos.AvgPrice.String(), tt.avg},
+			{"realized", pos.Realized.String(), tt.realized},
+			{"fees", pos.Fees.String(), tt.fees},
+			{"pnl", pos.PnL.String(), tt.pnl},
+		} {
+			if v.got != v.want {
+				t.Errorf("%v: expected %v %v, got %v", tt.name, v.field, v.want, v.got)
+			}
+		}
+
+		p.Close()
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"sort"
+	"time"
+)
+
+const (
+	OrderLimit  = OrderType("limit")
+	OrderMarket = OrderType("market")
+	OrderStop   = OrderType("stop")
+)
+
+const (
+	StopLoss  = StopType("loss")
+	StopEntry = StopType("entry")
+)
+
+const (
+	ReasonFilled   = DoneReason("filled")
+	ReasonCanceled = DoneReason("canceled")
+)
+
+type OrderType string
+type StopType string
+type DoneReason string
+
+type PendingOrder struct {
+	Id        string
+	Side      Side
+	OrderType OrderType
+	Price     decimal.Decimal
+	Size      decimal.Decimal
+	Funds     decimal.Decimal
+	StopType  StopType
+	StopPrice decimal.Decimal
+	Received  time.Time
+}
+
+func (p PendingOrder) Stop() bool {
+	return p.StopType != ""
+}
+
+func (o *OrderBook) PendingOrders() []PendingOrder {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	orders := make([]PendingOrder, 0, len(o.pending))
+	for _, p := range o.pending {
+		orders = append(orders, p)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].Received.Before(orders[j].Received)
+	})
+
+	return orders
+}
+
+func (o *OrderBook) received(msg Message) {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	p := o.pending[msg.OrderId]
+
+	p.Id = msg.OrderId
+	p.Side = msg.Side
+	p.OrderType = msg.OrderType
+	p.Price = msg.Price
+	p.Size = msg.Size
+	p.Funds = msg.Funds
+	p.Received = msg.Time
+
+	o.pending[p.Id] = p
+}
+
+func (o *OrderBook) activate(msg Message) {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	var p PendingOrder
+
+	p.Id = msg.OrderId
+	p.Side = msg.Side
+	p.OrderType = OrderStop
+	p.Size = msg.Size
+	p.Funds = msg.Funds
+	p.StopType = msg.StopType
+	p.StopPrice = msg.StopPrice
+	p.Received = msg.Time
+
+	o.pending[p.Id] = p
+}
+
+func (o *OrderBook) fillPending(msg Message) {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	p, ok := o.pending[msg.TakerOrderId]
+	if !ok {
+		return
+	}
+
+	if !p.Size.IsZero() {
+		p.Size = p.Size.Sub(msg.Size)
+	}
+
+	if !p.Funds.IsZero() {
+		p.Funds = p.Funds.Sub(msg.Size.Mul(msg.Price))
+	}
+
+	o.pending[p.Id] = p
+}
+
+func (o *OrderBook) changePending(msg Message) bool {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	p, ok := o.pending[msg.OrderId]
+	if !ok {
+		return false
+	}
+
+	if !msg.NewSize.IsZero() {
+		p.Size = msg.NewSize
+	}
+
+	if !msg.NewFunds.IsZero() {
+		p.Funds = msg.NewFunds
+	}
+
+	o.pending[p.Id] = p
+
+	return true
+}
+
+func (o *OrderBook) removePending(id string) {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	delete(o.pending, id)
+}
+
+func (o *OrderBook) clearPending() {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	for id, p := range o.pending {
+		if !p.Stop() {
+			delete(o.pending, id)
+		}
+	}
+}
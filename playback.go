@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointInterval = 5 * time.Second
+	jumpInterval       = 10 * time.Second
+)
+
+var playbackLock sync.Mutex
+var paused *OrderBook
+
+func viewBook() *OrderBook {
+	playbackLock.Lock()
+	defer playbackLock.Unlock()
+
+	if paused != nil {
+		return paused
+	}
+
+	return ob
+}
+
+func isPaused() bool {
+	playbackLock.Lock()
+	defer playbackLock.Unlock()
+
+	return paused != nil
+}
+
+func togglePause() {
+	playbackLock.Lock()
+
+	if paused != nil {
+		paused = nil
+		playbackLock.Unlock()
+
+		setTitle("")
+		refreshOrders()
+		return
+	}
+
+	b, err := ob.Reconstructor().AtSequence(ob.Product(), ob.Sequence())
+	if err == errNoHistory {
+		playbackLock.Unlock()
+		setTitle("Run with -history to pause")
+		return
+	}
+	if err != nil {
+		playbackLock.Unlock()
+		setTitle(err.Error())
+		return
+	}
+
+	paused = b
+	playbackLock.Unlock()
+
+	showPaused()
+}
+
+func stepForward() {
+	playbackLock.Lock()
+	if paused == nil {
+		playbackLock.Unlock()
+		return
+	}
+
+	ob.Reconstructor().Step(paused)
+	playbackLock.Unlock()
+
+	showPaused()
+}
+
+func jump(d time.Duration) {
+	playbackLock.Lock()
+	if paused == nil {
+		playbackLock.Unlock()
+		return
+	}
+
+	b, err := ob.Reconstructor().AtTime(paused.Product(), paused.Time().Add(d))
+	if err == nil {
+		paused = b
+	}
+	playbackLock.Unlock()
+
+	showPaused()
+}
+
+func showPaused() {
+	b := viewBook()
+
+	setTitle(fmt.Sprintf("PAUSED %v #%v", b.Time().Local().Format(timeFormat),
+		b.Sequence()))
+	refreshOrders()
+}
+
+func setTitle(title string) {
+	border := window.Border()
+	border.Title = title
+	window.SetBorder(border)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"math"
+	"sort"
+)
+
+const (
+	DefaultPriceScale = 8
+	maxPriceScale     = 18
+)
+
+var pow10 = [...]int64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11,
+	1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18}
+
+type priceIndex struct {
+	side   Side
+	ticks  []int64
+	levels map[int64]*priceLevel
+}
+
+type priceIterator struct {
+	index *priceIndex
+	i     int
+}
+
+func newPriceIndex(side Side) *priceIndex {
+	var p priceIndex
+
+	p.side = side
+	p.levels = make(map[int64]*priceLevel)
+
+	return &p
+}
+
+func toTicks(price decimal.Decimal, scale int32) int64 {
+	c := price.Coefficient()
+	exp := int(price.Exponent() + scale)
+
+	if !c.IsInt64() || exp >= len(pow10) || -exp >= len(pow10) {
+		return price.Shift(scale).IntPart()
+	}
+
+	n := c.Int64()
+	if exp <= 0 {
+		return n / pow10[-exp]
+	}
+
+	p := pow10[exp]
+	if n > math.MaxInt64/p || n < math.MinInt64/p {
+		return price.Shift(scale).IntPart()
+	}
+
+	return n * p
+}
+
+func (p *priceIndex) Get(tick int64) (*priceLevel, bool) {
+	l, ok := p.levels[tick]
+	return l, ok
+}
+
+func (p *priceIndex) Put(tick int64, l *priceLevel) {
+	if _, ok := p.levels[tick]; !ok {
+		i := p.search(tick)
+
+		p.ticks = append(p.ticks, 0)
+		copy(p.ticks[i+1:], p.ticks[i:])
+		p.ticks[i] = tick
+	}
+
+	p.levels[tick] = l
+}
+
+func (p *priceIndex) Remove(tick int64) {
+	if _, ok := p.levels[tick]; !ok {
+		return
+	}
+
+	delete(p.levels, tick)
+
+	i := p.search(tick)
+	p.ticks = append(p.ticks[:i], p.ticks[i+1:]...)
+}
+
+func (p *priceIndex) Clear() {
+	p.ticks = p.ticks[:0]
+	p.levels = make(map[int64]*priceLevel)
+}
+
+func (p *priceIndex) Size() int {
+	return len(p.ticks)
+}
+
+func (p *priceIndex) Iterator() *priceIterator {
+	return &priceIterator{p, len(p.ticks)}
+}
+
+func (p *priceIndex) search(tick int64) int {
+	if p.side == Buy {
+		return sort.Search(len(p.ticks), func(i int) bool {
+			return p.ticks[i] >= tick
+		})
+	}
+
+	return sort.Search(len(p.ticks), func(i int) bool {
+		return p.ticks[i] <= tick
+	})
+}
+
+func (it *priceIterator) Next() bool {
+	if it.i <= 0 {
+		return false
+	}
+
+	it.i--
+
+	return true
+}
+
+func (it *priceIterator) Tick() int64 {
+	return it.index.ticks[it.i]
+}
+
+func (it *priceIterator) Value() *priceLevel {
+	return it.index.levels[it.index.ticks[it.i]]
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"testing"
+)
+
+func TestToTicks(t *testing.T) {
+	tests := []struct {
+		price string
+		scale int32
+		ticks int64
+	}{
+		{"0", 8, 0},
+		{"3512.45", 2, 351245},
+		{"3512.45", 8, 351245000000},
+		{"3512.456", 2, 351245},
+		{"-5.5", 0, -5},
+		{"0.00000001", 8, 1},
+		{"0.000000019", 8, 1},
+		{"1e-30", 8, 0},
+		{"123456789012.123456789", 6, 123456789012123456},
+		{"12345.6789", 12, 12345678900000000},
+	}
+
+	for _, tt := range tests {
+		got := toTicks(decimal.RequireFromString(tt.price), tt.scale)
+		if got != tt.ticks {
+			t.Errorf("toTicks(%v, %v) = %v, expected %v", tt.price, tt.scale, got, tt.ticks)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"errors"
+	"sync"
 )
 
 type Queue struct {
+	lock sync.Mutex
+
 	data         []interface{}
 	begin, end   int
 	length, size int
@@ -20,10 +23,31 @@ func NewQueue() *Queue {
 }
 
 func (q *Queue) Length() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
 	return q.length
 }
 
+func (q *Queue) Push(v interface{}) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.length == q.size {
+		q.dequeue()
+	}
+
+	q.enqueue(v)
+}
+
 func (q *Queue) Enqueue(v interface{}) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.enqueue(v)
+}
+
+func (q *Queue) enqueue(v interface{}) error {
 	if q.length == 256 {
 		return errors.New("Queue Full")
 	}
@@ -41,6 +65,13 @@ func (q *Queue) Enqueue(v interface{}) error {
 }
 
 func (q *Queue) Dequeue() interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.dequeue()
+}
+
+func (q *Queue) dequeue() interface{} {
 	if q.length == 0 {
 		return nil
 	}
@@ -63,6 +94,29 @@ func (q *Queue) Dequeue() interface{} {
 }
 
 func (q *Queue) Element(i int) interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.element(i)
+}
+
+func (q *Queue) Last(n int) []interface{} {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if n <= 0 || n > q.length {
+		n = q.length
+	}
+
+	elements := make([]interface{}, 0, n)
+	for i := q.length - 1; i >= q.length-n; i-- {
+		elements = append(elements, q.element(i))
+	}
+
+	return elements
+}
+
+func (q *Queue) element(i int) interface{} {
 	var v interface{}
 
 	if i > q.length {
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RecordFrame    = "frame"
+	RecordSnapshot = "snapshot"
+)
+
+type CaptureRecord struct {
+	Time    time.Time       `json:"time"`
+	Kind    string          `json:"kind"`
+	Product string          `json:"product,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type Recorder struct {
+	lock sync.Mutex
+
+	file *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	var r Recorder
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r.file = f
+
+	var w io.Writer = f
+	if isGzip(path) {
+		r.gz = gzip.NewWriter(f)
+		w = r.gz
+	}
+
+	r.enc = json.NewEncoder(w)
+
+	return &r, nil
+}
+
+func (r *Recorder) Frame(t time.Time, data []byte) error {
+	return r.write(CaptureRecord{Time: t, Kind: RecordFrame, Data: data})
+}
+
+func (r *Recorder) Snapshot(t time.Time, product string, data []byte) error {
+	return r.write(CaptureRecord{Time: t, Kind: RecordSnapshot,
+		Product: product, Data: data})
+}
+
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.gz != nil {
+		err := r.gz.Close()
+		if err != nil {
+			r.file.Close()
+			return err
+		}
+	}
+
+	return r.file.Close()
+}
+
+func (r *Recorder) write(rec CaptureRecord) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.enc.Encode(rec)
+}
+
+func isGzip(path string) bool {
+	return strings.HasSuffix(path, ".gz")
+}
+
+type captureReader struct {
+	file *os.File
+	gz   *gzip.Reader
+	dec  *json.Decoder
+}
+
+func openCapture(path string) (*captureReader, error) {
+	var c captureReader
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.file = f
+
+	var r io.Reader = f
+	if isGzip(path) {
+		c.gz, err = gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		r = c.gz
+	}
+
+	c.dec = json.NewDecoder(r)
+
+	return &c, nil
+}
+
+func (c *captureReader) Next() (CaptureRecord, error) {
+	var rec CaptureRecord
+
+	err := c.dec.Decode(&rec)
+
+	return rec, err
+}
+
+func (c *captureReader) Close() error {
+	if c.gz != nil {
+		c.gz.Close()
+	}
+
+	return c.file.Close()
+}
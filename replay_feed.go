@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type ReplayFeed struct {
+	path  string
+	speed float64
+
+	snapLock  sync.Mutex
+	snapshots map[string][]FeedSnapshot
+
+	lock    sync.Mutex
+	capture *captureReader
+	closed  bool
+	done    chan struct{}
+
+	last time.Time
+}
+
+func NewReplayFeed(path string, speed float64) (*ReplayFeed, error) {
+	var f ReplayFeed
+
+	f.path = path
+	f.speed = speed
+	f.snapshots = make(map[string][]FeedSnapshot)
+
+	c, err := openCapture(path)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	for {
+		rec, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if rec.Kind != RecordSnapshot {
+			continue
+		}
+
+		snap, err := parseSnapshot(rec.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		f.snapshots[rec.Product] = append(f.snapshots[rec.Product], snap)
+	}
+
+	return &f, nil
+}
+
+func (f *ReplayFeed) Connect() error {
+	c, err := openCapture(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.capture != nil {
+		f.capture.Close()
+	}
+
+	f.capture = c
+	f.closed = false
+	f.done = make(chan struct{})
+	f.last = time.Time{}
+
+	return nil
+}
+
+func (f *ReplayFeed) Subscribe(products []string, channels []string) error {
+	return nil
+}
+
+func (f *ReplayFeed) Snapshot(product string) (FeedSnapshot, error) {
+	f.snapLock.Lock()
+	defer f.snapLock.Unlock()
+
+	snaps := f.snapshots[product]
+	if len(snaps) == 0 {
+		return FeedSnapshot{}, fmt.Errorf("No snapshot recorded for %v", product)
+	}
+
+	snap := snaps[0]
+	if len(snaps) > 1 {
+		f.snapshots[product] = snaps[1:]
+	}
+
+	return snap, nil
+}
+
+func (f *ReplayFeed) Read() (Message, error) {
+	var msg Message
+
+	for {
+		f.lock.Lock()
+		c, done, closed := f.capture, f.done, f.closed
+		f.lock.Unlock()
+
+		if c == nil || closed {
+			return msg, io.EOF
+		}
+
+		rec, err := c.Next()
+		if err == io.EOF {
+			<-done
+			return msg, io.EOF
+		}
+		if err != nil {
+			if f.isClosed() {
+				return msg, io.EOF
+			}
+
+			return msg, err
+		}
+
+		if rec.Kind != RecordFrame {
+			continue
+		}
+
+		f.pace(rec.Time, done)
+
+		return decodeMessage(rec.Data)
+	}
+}
+
+func (f *ReplayFeed) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return nil
+	}
+
+	f.closed = true
+
+	if f.capture == nil {
+		return nil
+	}
+
+	close(f.done)
+
+	return f.capture.Close()
+}
+
+func (f *ReplayFeed) pace(t time.Time, done <-chan struct{}) {
+	if f.speed <= 0 {
+		return
+	}
+
+	if !f.last.IsZero() && t.After(f.last) {
+		d := time.Duration(float64(t.Sub(f.last)) / f.speed)
+
+		select {
+		case <-time.After(d):
+		case <-done:
+		}
+	}
+
+	f.last = t
+}
+
+func (f *ReplayFeed) isClosed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.closed
+}
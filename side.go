@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	Buy  = Side(false)
+	Sell = Side(true)
+)
+
+type Side bool
+
+func ParseSide(s string) (Side, error) {
+	switch s {
+	case "buy":
+		return Buy, nil
+	case "sell":
+		return Sell, nil
+	default:
+		return Buy, fmt.Errorf("Unknown side: %q", s)
+	}
+}
+
+func (s Side) String() string {
+	if s == Sell {
+		return "sell"
+	}
+
+	return "buy"
+}
+
+func (s Side) Opposite() Side {
+	return !s
+}
+
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Side) UnmarshalText(b []byte) error {
+	side, err := ParseSide(string(b))
+	if err != nil {
+		return err
+	}
+
+	*s = side
+
+	return nil
+}
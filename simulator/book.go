@@ -0,0 +1,269 @@
+package simulator
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+	"sort"
+)
+
+type order struct {
+	id    string
+	side  Side
+	price decimal.Decimal
+	size  decimal.Decimal
+}
+
+type level struct {
+	price  decimal.Decimal
+	orders []*order
+}
+
+type book struct {
+	product  Product
+	sequence int64
+	trade    int64
+
+	bids   []*level
+	asks   []*level
+	orders map[string]*order
+}
+
+func newBook(p Product) *book {
+	var b book
+
+	b.product = p
+	b.orders = make(map[string]*order)
+
+	return &b
+}
+
+func (b *book) side(s Side) *[]*level {
+	if s == Buy {
+		return &b.bids
+	}
+
+	return &b.asks
+}
+
+func better(s Side, a, b decimal.Decimal) bool {
+	if s == Buy {
+		return a.GreaterThan(b)
+	}
+
+	return a.LessThan(b)
+}
+
+func crosses(taker Side, limit, price decimal.Decimal) bool {
+	if taker == Buy {
+		return price.LessThanOrEqual(limit)
+	}
+
+	return price.GreaterThanOrEqual(limit)
+}
+
+func (b *book) best(s Side) (decimal.Decimal, bool) {
+	levels := *b.side(s)
+	if len(levels) == 0 {
+		return decimal.Zero, false
+	}
+
+	return levels[0].price, true
+}
+
+func (b *book) mid() decimal.Decimal {
+	bid, hasBid := b.best(Buy)
+	ask, hasAsk := b.best(Sell)
+
+	switch {
+	case hasBid && hasAsk:
+		return bid.Add(ask).Div(decimal.NewFromInt(2)).
+			Round(b.product.QuoteIncrement.Exponent() * -1)
+	case hasBid:
+		return bid
+	case hasAsk:
+		return ask
+	default:
+		return b.product.Price
+	}
+}
+
+func (b *book) rest(o *order) {
+	levels := b.side(o.side)
+
+	i := sort.Search(len(*levels), func(i int) bool {
+		return !better(o.side, (*levels)[i].price, o.price)
+	})
+
+	if i < len(*levels) && (*levels)[i].price.Equal(o.price) {
+		(*levels)[i].orders = append((*levels)[i].orders, o)
+	} else {
+		l := &level{price: o.price, orders: []*order{o}}
+
+		*levels = append(*levels, nil)
+		copy((*levels)[i+1:], (*levels)[i:])
+		(*levels)[i] = l
+	}
+
+	b.orders[o.id] = o
+}
+
+func (b *book) remove(o *order) {
+	levels := b.side(o.side)
+
+	for i, l := range *levels {
+		if !l.price.Equal(o.price) {
+			continue
+		}
+
+		for j, r := range l.orders {
+			if r == o {
+				l.orders = append(l.orders[:j], l.orders[j+1:]...)
+				break
+			}
+		}
+
+		if len(l.orders) == 0 {
+			*levels = append((*levels)[:i], (*levels)[i+1:]...)
+		}
+
+		break
+	}
+
+	delete(b.orders, o.id)
+}
+
+func (b *book) limit(id string, side Side, price,
+	size decimal.Decimal) ([]Message, error) {
+	if !size.IsPositive() || !price.IsPositive() {
+		return nil, fmt.Errorf("Invalid limit order: %v @ %v", size, price)
+	}
+
+	if _, ok := b.orders[id]; ok {
+		return nil, fmt.Errorf("Duplicate order id: %v", id)
+	}
+
+	msgs := []Message{{Type: "received", OrderId: id, OrderType: "limit",
+		Side: side, Price: price.String(), Size: size.String()}}
+
+	remaining, fills := b.take(id, side, price, true, size)
+	msgs = append(msgs, fills...)
+
+	if remaining.IsPositive() {
+		b.rest(&order{id, side, price, remaining})
+
+		msgs = append(msgs, Message{Type: "open", OrderId: id, Side: side,
+			Price: price.String(), RemainingSize: remaining.String()})
+	} else {
+		msgs = append(msgs, Message{Type: "done", OrderId: id, Side: side,
+			Price: price.String(), RemainingSize: "0", Reason: "filled"})
+	}
+
+	return msgs, nil
+}
+
+func (b *book) market(id string, side Side, size decimal.Decimal) ([]Message, error) {
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("Invalid market order: %v", size)
+	}
+
+	msgs := []Message{{Type: "received", OrderId: id, OrderType: "market",
+		Side: side, Size: size.String()}}
+
+	remaining, fills := b.take(id, side, decimal.Zero, false, size)
+	msgs = append(msgs, fills...)
+
+	reason := "filled"
+	if remaining.IsPositive() {
+		reason = "canceled"
+	}
+
+	msgs = append(msgs, Message{Type: "done", OrderId: id, Side: side,
+		RemainingSize: remaining.String(), Reason: reason})
+
+	return msgs, nil
+}
+
+func (b *book) take(id string, side Side, limit decimal.Decimal, limited bool,
+	size decimal.Decimal) (decimal.Decimal, []Message) {
+	var msgs []Message
+
+	levels := b.side(side.Opposite())
+
+	for size.IsPositive() && len(*levels) > 0 {
+		l := (*levels)[0]
+		if limited && !crosses(side, limit, l.price) {
+			break
+		}
+
+		maker := l.orders[0]
+
+		fill := decimal.Min(size, maker.size)
+		size = size.Sub(fill)
+		maker.size = maker.size.Sub(fill)
+
+		b.trade++
+		msgs = append(msgs, Message{Type: "match", TradeId: b.trade,
+			MakerOrderId: maker.id, TakerOrderId: id, Side: maker.side,
+			Price: maker.price.String(), Size: fill.String()})
+
+		if !maker.size.IsPositive() {
+			b.remove(maker)
+
+			msgs = append(msgs, Message{Type: "done", OrderId: maker.id,
+				Side: maker.side, Price: maker.price.String(),
+				RemainingSize: "0", Reason: "filled"})
+		}
+	}
+
+	return size, msgs
+}
+
+func (b *book) cancel(id string) ([]Message, error) {
+	o, ok := b.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown order: %v", id)
+	}
+
+	b.remove(o)
+
+	return []Message{{Type: "done", OrderId: id, Side: o.side,
+		Price: o.price.String(), RemainingSize: o.size.String(),
+		Reason: "canceled"}}, nil
+}
+
+func (b *book) change(id string, size decimal.Decimal) ([]Message, error) {
+	o, ok := b.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("Unknown order: %v", id)
+	}
+
+	if !size.IsPositive() || !size.LessThan(o.size) {
+		return nil, fmt.Errorf("Invalid size change: %v to %v", o.size, size)
+	}
+
+	old := o.size
+	o.size = size
+
+	return []Message{{Type: "change", OrderId: id, Side: o.side,
+		Price: o.price.String(), NewSize: size.String(),
+		OldSize: old.String()}}, nil
+}
+
+func (b *book) snapshot() LevelThree {
+	snap := LevelThree{Sequence: b.sequence, Bids: [][]string{}, Asks: [][]string{}}
+
+	for _, l := range b.bids {
+		for _, o := range l.orders {
+			snap.Bids = append(snap.Bids, []string{o.price.String(), o.size.String(), o.id})
+		}
+	}
+
+	for _, l := range b.asks {
+		for _, o := range l.orders {
+			snap.Asks = append(snap.Asks, []string{o.price.String(), o.size.String(), o.id})
+		}
+	}
+
+	return snap
+}
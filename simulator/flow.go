@@ -0,0 +1,201 @@
+package simulator
+
+import (
+	"github.com/shopspring/decimal"
+
+	"context"
+	"fmt"
+	"time"
+)
+
+type ActionKind int
+
+const (
+	ActionLimit ActionKind = iota
+	ActionMarket
+	ActionCancel
+	ActionChange
+)
+
+type Action struct {
+	Delay   time.Duration
+	Kind    ActionKind
+	Product string
+	Id      string
+	Side    Side
+	Price   decimal.Decimal
+	Size    decimal.Decimal
+}
+
+const (
+	maxOffset   = 20
+	targetDepth = 200
+)
+
+func (s *Simulator) Play(ctx context.Context, actions []Action) error {
+	for _, a := range actions {
+		if a.Delay > 0 && !sleep(ctx, a.Delay) {
+			return ctx.Err()
+		}
+
+		err := s.Apply(a)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Simulator) Apply(a Action) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if a.Product == "" && len(s.ids) > 0 {
+		a.Product = s.ids[0]
+	}
+
+	switch a.Kind {
+	case ActionLimit:
+		if a.Id == "" {
+			a.Id = s.orderId()
+		}
+
+		return s.limit(a.Product, a.Id, a.Side, a.Price, a.Size)
+	case ActionMarket:
+		if a.Id == "" {
+			a.Id = s.orderId()
+		}
+
+		return s.market(a.Product, a.Id, a.Side, a.Size)
+	case ActionCancel:
+		return s.cancel(a.Product, a.Id)
+	case ActionChange:
+		return s.change(a.Product, a.Id, a.Size)
+	default:
+		return fmt.Errorf("Unknown action: %v", a.Kind)
+	}
+}
+
+func (s *Simulator) Populate(depth int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, id := range s.ids {
+		b := s.books[id]
+		tick := b.product.QuoteIncrement
+		mid := b.mid()
+
+		for i := 1; i <= depth; i++ {
+			offset := tick.Mul(decimal.NewFromInt(int64(i)))
+
+			for n := s.rand.Intn(3); n >= 0; n-- {
+				s.limit(id, s.orderId(), Buy, mid.Sub(offset), s.randomSize(b))
+				s.limit(id, s.orderId(), Sell, mid.Add(offset), s.randomSize(b))
+			}
+		}
+	}
+}
+
+func (s *Simulator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Step()
+		}
+	}
+}
+
+func (s *Simulator) Step() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, id := range s.ids {
+		s.step(s.books[id])
+	}
+}
+
+func (s *Simulator) step(b *book) {
+	id := b.product.Id
+	tick := b.product.QuoteIncrement
+	mid := b.mid()
+	side := Buy
+	if s.rand.Intn(2) == 1 {
+		side = Sell
+	}
+
+	r := s.rand.Float64()
+	if len(b.orders) > targetDepth {
+		r = 0.7 + r*0.3
+	}
+
+	switch {
+	case r < 0.55:
+		offset := tick.Mul(decimal.NewFromInt(int64(s.rand.Intn(maxOffset) + 1)))
+		price := mid.Sub(offset)
+		if side == Sell {
+			price = mid.Add(offset)
+		}
+
+		s.limit(id, s.orderId(), side, price, s.randomSize(b))
+	case r < 0.65:
+		offset := tick.Mul(decimal.NewFromInt(int64(s.rand.Intn(3))))
+		price := mid.Add(offset)
+		if side == Sell {
+			price = mid.Sub(offset)
+		}
+
+		s.limit(id, s.orderId(), side, price, s.randomSize(b))
+	case r < 0.72:
+		s.market(id, s.orderId(), side, s.randomSize(b))
+	case r < 0.95:
+		o, ok := s.randomOrder(b)
+		if ok {
+			s.cancel(id, o.id)
+		}
+	default:
+		o, ok := s.randomOrder(b)
+		if ok {
+			s.change(id, o.id, o.size.Div(decimal.NewFromInt(2)).
+				Truncate(b.product.BaseIncrement.Exponent()*-1))
+		}
+	}
+}
+
+func (s *Simulator) randomSize(b *book) decimal.Decimal {
+	size := decimal.NewFromFloat(0.01 + s.rand.Float64()*2)
+
+	return size.Truncate(b.product.BaseIncrement.Exponent() * -1)
+}
+
+func (s *Simulator) randomOrder(b *book) (*order, bool) {
+	levels := b.bids
+	if s.rand.Intn(2) == 1 {
+		levels = b.asks
+	}
+
+	if len(levels) == 0 {
+		return nil, false
+	}
+
+	l := levels[s.rand.Intn(len(levels))]
+
+	return l.orders[s.rand.Intn(len(l.orders))], true
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,68 @@
+package simulator
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+type Side string
+
+const (
+	Buy  Side = "buy"
+	Sell Side = "sell"
+)
+
+func (s Side) Opposite() Side {
+	if s == Buy {
+		return Sell
+	}
+
+	return Buy
+}
+
+type Message struct {
+	Type          string    `json:"type"`
+	Sequence      int64     `json:"sequence,omitempty"`
+	ProductId     string    `json:"product_id,omitempty"`
+	Time          string    `json:"time,omitempty"`
+	OrderId       string    `json:"order_id,omitempty"`
+	OrderType     string    `json:"order_type,omitempty"`
+	Side          Side      `json:"side,omitempty"`
+	Price         string    `json:"price,omitempty"`
+	Size          string    `json:"size,omitempty"`
+	RemainingSize string    `json:"remaining_size,omitempty"`
+	NewSize       string    `json:"new_size,omitempty"`
+	OldSize       string    `json:"old_size,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+	TradeId       int64     `json:"trade_id,omitempty"`
+	LastTradeId   int64     `json:"last_trade_id,omitempty"`
+	MakerOrderId  string    `json:"maker_order_id,omitempty"`
+	TakerOrderId  string    `json:"taker_order_id,omitempty"`
+	Channels      []Channel `json:"channels,omitempty"`
+	Message       string    `json:"message,omitempty"`
+}
+
+type Channel struct {
+	Name       string   `json:"name"`
+	ProductIds []string `json:"product_ids"`
+}
+
+type Sub struct {
+	Type       string   `json:"type"`
+	ProductIds []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+}
+
+type Product struct {
+	Id             string          `json:"id"`
+	BaseCurrency   string          `json:"base_currency"`
+	QuoteCurrency  string          `json:"quote_currency"`
+	BaseIncrement  decimal.Decimal `json:"base_increment"`
+	QuoteIncrement decimal.Decimal `json:"quote_increment"`
+	Price          decimal.Decimal `json:"-"`
+}
+
+type LevelThree struct {
+	Sequence int64      `json:"sequence"`
+	Bids     [][]string `json:"bids"`
+	Asks     [][]string `json:"asks"`
+}
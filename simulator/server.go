@@ -0,0 +1,327 @@
+package simulator
+
+import (
+	"github.com/gorilla/websocket"
+
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clientBuffer      = 4096
+	heartbeatInterval = time.Second
+)
+
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+
+	productsLock sync.Mutex
+	products     map[string]bool
+	heartbeat    bool
+
+	closeOnce sync.Once
+}
+
+func (s *Simulator) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	s.listener = ln
+	s.server = &http.Server{Handler: s}
+	s.stop = make(chan struct{})
+
+	go s.server.Serve(ln)
+	go s.heartbeats()
+
+	return nil
+}
+
+func (s *Simulator) heartbeats() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.heartbeat()
+		}
+	}
+}
+
+func (s *Simulator) heartbeat() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	t := s.now().UTC().Format(time.RFC3339Nano)
+
+	for _, id := range s.ids {
+		b := s.books[id]
+
+		s.broadcast(Message{Type: "heartbeat", Sequence: b.sequence,
+			LastTradeId: b.trade, ProductId: id, Time: t})
+	}
+}
+
+func (s *Simulator) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+
+	return s.listener.Addr().String()
+}
+
+func (s *Simulator) WebsocketURL() string {
+	return "ws://" + s.Addr()
+}
+
+func (s *Simulator) RestURL() string {
+	return "http://" + s.Addr()
+}
+
+func (s *Simulator) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
+	close(s.stop)
+	err := s.server.Close()
+
+	s.clientsLock.Lock()
+	for c := range s.clients {
+		c.close()
+	}
+	s.clients = make(map[*client]struct{})
+	s.clientsLock.Unlock()
+
+	return err
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveFeed(w, r)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "products" || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		s.serveProduct(w, parts[1])
+	case len(parts) == 3 && parts[2] == "book":
+		s.serveBook(w, r, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Simulator) serveProduct(w http.ResponseWriter, id string) {
+	s.lock.Lock()
+	b, ok := s.books[id]
+	s.lock.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound")
+		return
+	}
+
+	writeJSON(w, b.product)
+}
+
+func (s *Simulator) serveBook(w http.ResponseWriter, r *http.Request, id string) {
+	if r.URL.Query().Get("level") != "3" {
+		writeError(w, http.StatusBadRequest, "Only level 3 is supported")
+		return
+	}
+
+	snap, err := s.Snapshot(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "NotFound")
+		return
+	}
+
+	writeJSON(w, snap)
+}
+
+func (s *Simulator) serveFeed(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &client{conn: conn, send: make(chan []byte, clientBuffer),
+		products: make(map[string]bool)}
+
+	s.clientsLock.Lock()
+	s.clients[c] = struct{}{}
+	s.clientsLock.Unlock()
+
+	go c.write()
+	s.read(c)
+}
+
+func (s *Simulator) read(c *client) {
+	defer s.drop(c)
+
+	for {
+		var sub Sub
+
+		err := c.conn.ReadJSON(&sub)
+		if err != nil {
+			return
+		}
+
+		msg := s.subscribe(c, sub)
+
+		buf, err := json.Marshal(msg)
+		if err != nil {
+			return
+		}
+
+		if !s.reply(c, buf) {
+			return
+		}
+	}
+}
+
+func (s *Simulator) reply(c *client, buf []byte) bool {
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+
+	if _, ok := s.clients[c]; !ok {
+		return false
+	}
+
+	return c.deliver(buf)
+}
+
+func (s *Simulator) subscribe(c *client, sub Sub) Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, p := range sub.ProductIds {
+		if _, ok := s.books[p]; !ok {
+			return Message{Type: "error", Message: "Failed to subscribe",
+				Reason: p + " is not a valid product"}
+		}
+	}
+
+	c.productsLock.Lock()
+	defer c.productsLock.Unlock()
+
+	for _, p := range sub.ProductIds {
+		c.products[p] = sub.Type != "unsubscribe"
+	}
+
+	for _, ch := range sub.Channels {
+		if ch == "heartbeat" {
+			c.heartbeat = sub.Type != "unsubscribe"
+		}
+	}
+
+	var products []string
+	for p, ok := range c.products {
+		if ok {
+			products = append(products, p)
+		}
+	}
+
+	channels := []Channel{{Name: "full", ProductIds: products}}
+	if c.heartbeat {
+		channels = append(channels, Channel{Name: "heartbeat", ProductIds: products})
+	}
+
+	return Message{Type: "subscriptions", Channels: channels}
+}
+
+func (s *Simulator) broadcast(msg Message) {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	s.clientsLock.Lock()
+	defer s.clientsLock.Unlock()
+
+	for c := range s.clients {
+		if !c.subscribed(msg) {
+			continue
+		}
+
+		if !c.deliver(buf) {
+			delete(s.clients, c)
+			c.close()
+		}
+	}
+}
+
+func (s *Simulator) drop(c *client) {
+	s.clientsLock.Lock()
+	delete(s.clients, c)
+	s.clientsLock.Unlock()
+
+	c.close()
+}
+
+func (c *client) subscribed(msg Message) bool {
+	c.productsLock.Lock()
+	defer c.productsLock.Unlock()
+
+	if msg.Type == "heartbeat" && !c.heartbeat {
+		return false
+	}
+
+	return c.products[msg.ProductId]
+}
+
+func (c *client) deliver(buf []byte) bool {
+	select {
+	case c.send <- buf:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) write() {
+	for buf := range c.send {
+		err := c.conn.WriteMessage(websocket.TextMessage, buf)
+		if err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+
+	c.conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.conn.Close()
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.send)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,223 @@
+package simulator
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Products []Product
+	Seed     int64
+	Now      func() time.Time
+}
+
+type Simulator struct {
+	lock  sync.Mutex
+	books map[string]*book
+	ids   []string
+	rand  *rand.Rand
+	next  int64
+	now   func() time.Time
+
+	clientsLock sync.Mutex
+	clients     map[*client]struct{}
+
+	listener net.Listener
+	server   *http.Server
+	stop     chan struct{}
+	upgrader websocket.Upgrader
+}
+
+func DefaultProduct() Product {
+	return Product{
+		Id:             "ETH-USD",
+		BaseCurrency:   "ETH",
+		QuoteCurrency:  "USD",
+		BaseIncrement:  decimal.New(1, -8),
+		QuoteIncrement: decimal.New(1, -2),
+		Price:          decimal.NewFromInt(200),
+	}
+}
+
+func New(cfg Config) *Simulator {
+	var s Simulator
+
+	if len(cfg.Products) == 0 {
+		cfg.Products = []Product{DefaultProduct()}
+	}
+
+	s.now = cfg.Now
+	if s.now == nil {
+		s.now = time.Now
+	}
+
+	s.rand = rand.New(rand.NewSource(cfg.Seed))
+	s.books = make(map[string]*book)
+	s.clients = make(map[*client]struct{})
+
+	for _, p := range cfg.Products {
+		if _, ok := s.books[p.Id]; ok {
+			continue
+		}
+
+		s.books[p.Id] = newBook(p)
+		s.ids = append(s.ids, p.Id)
+	}
+
+	return &s
+}
+
+func (s *Simulator) Products() []string {
+	return append([]string{}, s.ids...)
+}
+
+func (s *Simulator) Limit(product string, side Side, price,
+	size decimal.Decimal) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := s.orderId()
+
+	return id, s.limit(product, id, side, price, size)
+}
+
+func (s *Simulator) Market(product string, side Side,
+	size decimal.Decimal) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	id := s.orderId()
+
+	return id, s.market(product, id, side, size)
+}
+
+func (s *Simulator) Cancel(product, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.cancel(product, id)
+}
+
+func (s *Simulator) Change(product, id string, size decimal.Decimal) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.change(product, id, size)
+}
+
+func (s *Simulator) Snapshot(product string) (LevelThree, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, ok := s.books[product]
+	if !ok {
+		return LevelThree{}, fmt.Errorf("Unknown product: %v", product)
+	}
+
+	return b.snapshot(), nil
+}
+
+func (s *Simulator) Sequence(product string) int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, ok := s.books[product]
+	if !ok {
+		return 0
+	}
+
+	return b.sequence
+}
+
+func (s *Simulator) limit(product, id string, side Side, price,
+	size decimal.Decimal) error {
+	b, ok := s.books[product]
+	if !ok {
+		return fmt.Errorf("Unknown product: %v", product)
+	}
+
+	msgs, err := b.limit(id, side, price, size)
+	if err != nil {
+		return err
+	}
+
+	s.emit(b, msgs)
+
+	return nil
+}
+
+func (s *Simulator) market(product, id string, side Side,
+	size decimal.Decimal) error {
+	b, ok := s.books[product]
+	if !ok {
+		return fmt.Errorf("Unknown product: %v", product)
+	}
+
+	msgs, err := b.market(id, side, size)
+	if err != nil {
+		return err
+	}
+
+	s.emit(b, msgs)
+
+	return nil
+}
+
+func (s *Simulator) cancel(product, id string) error {
+	b, ok := s.books[product]
+	if !ok {
+		return fmt.Errorf("Unknown product: %v", product)
+	}
+
+	msgs, err := b.cancel(id)
+	if err != nil {
+		return err
+	}
+
+	s.emit(b, msgs)
+
+	return nil
+}
+
+func (s *Simulator) change(product, id string, size decimal.Decimal) error {
+	b, ok := s.books[product]
+	if !ok {
+		return fmt.Errorf("Unknown product: %v", product)
+	}
+
+	msgs, err := b.change(id, size)
+	if err != nil {
+		return err
+	}
+
+	s.emit(b, msgs)
+
+	return nil
+}
+
+func (s *Simulator) emit(b *book, msgs []Message) {
+	t := s.now().UTC().Format(time.RFC3339Nano)
+
+	for _, msg := range msgs {
+		b.sequence++
+
+		msg.Sequence = b.sequence
+		msg.ProductId = b.product.Id
+		msg.Time = t
+
+		s.broadcast(msg)
+	}
+}
+
+func (s *Simulator) orderId() string {
+	s.next++
+
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", s.rand.Uint32(), s.next)
+}
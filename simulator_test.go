@@ -0,0 +1,107 @@
+package main
+
+import (
+	"git.cotugno.family/kevin/spectator/simulator"
+	"github.com/shopspring/decimal"
+
+	"context"
+	"testing"
+	"time"
+)
+
+func simulatorLevels(t *testing.T, entries [][]string) []Level {
+	var levels []Level
+
+	for _, e := range entries {
+		price, err := decimal.NewFromString(e[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		size, err := decimal.NewFromString(e[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n := len(levels)
+		if n > 0 && levels[n-1].Price.Equal(price) {
+			levels[n-1].Size = levels[n-1].Size.Add(size)
+			levels[n-1].Count++
+			continue
+		}
+
+		levels = append(levels, Level{Price: price, Size: size, Count: 1})
+	}
+
+	return levels
+}
+
+func compareLevels(t *testing.T, side Side, want, got []Level) {
+	t.Helper()
+
+	if len(want) != len(got) {
+		t.Fatalf("Expected %v %v levels, got %v", len(want), side, len(got))
+	}
+
+	for i := range want {
+		if !want[i].Price.Equal(got[i].Price) || !want[i].Size.Equal(got[i].Size) ||
+			want[i].Count != got[i].Count {
+			t.Errorf("%v level %v: expected %v x %v (%v), got %v x %v (%v)", side, i,
+				want[i].Size, want[i].Price, want[i].Count,
+				got[i].Size, got[i].Price, got[i].Count)
+		}
+	}
+}
+
+func TestSimulatorEndToEnd(t *testing.T) {
+	sim := simulator.New(simulator.Config{Seed: 7})
+	sim.Populate(20)
+
+	if err := sim.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+
+	ob, err := NewOrderBook(context.Background(), "ETH-USD", Options{
+		WebsocketURL: sim.WebsocketURL(),
+		RestURL:      sim.RestURL(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ob.Close()
+
+	waitState(t, ob.State, StateLive)
+
+	for i := 0; i < 2000; i++ {
+		sim.Step()
+	}
+
+	want := sim.Sequence("ETH-USD")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ob.Sequence() != want && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if seq := ob.Sequence(); seq != want {
+		t.Fatalf("Expected sequence %v, got %v", want, seq)
+	}
+
+	l3, err := sim.Snapshot("ETH-USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := ob.Snapshot(1 << 20)
+	if len(snap.Bids) == 0 || len(snap.Asks) == 0 {
+		t.Fatal("Expected both sides of the book to be populated")
+	}
+
+	compareLevels(t, Buy, simulatorLevels(t, l3.Bids), snap.Bids)
+	compareLevels(t, Sell, simulatorLevels(t, l3.Asks), snap.Asks)
+
+	if stats := ob.Stats(); stats.Gaps != 0 {
+		t.Errorf("Expected no gaps, got %v", stats.Gaps)
+	}
+}
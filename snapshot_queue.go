@@ -0,0 +1,110 @@
+package main
+
+import (
+	"time"
+)
+
+type snapshotResult struct {
+	product string
+	snap    FeedSnapshot
+	err     error
+}
+
+type snapshotRequest struct {
+	product string
+	results chan<- snapshotResult
+}
+
+type snapshotQueue struct {
+	Synced  <-chan snapshotResult
+	Checked <-chan snapshotResult
+
+	feed     Feed
+	interval time.Duration
+
+	synced   chan snapshotResult
+	checked  chan snapshotResult
+	requests chan snapshotRequest
+	quit     chan struct{}
+}
+
+func newSnapshotQueue(feed Feed, interval time.Duration, books int) *snapshotQueue {
+	var q snapshotQueue
+
+	q.feed = feed
+	q.interval = interval
+
+	q.synced = make(chan snapshotResult, books)
+	q.Synced = q.synced
+	q.checked = make(chan snapshotResult, books)
+	q.Checked = q.checked
+
+	q.requests = make(chan snapshotRequest, 2*books)
+	q.quit = make(chan struct{})
+
+	go q.run()
+
+	return &q
+}
+
+func (q *snapshotQueue) Close() {
+	close(q.quit)
+}
+
+func (q *snapshotQueue) Sync(product string, delay time.Duration) {
+	q.request(snapshotRequest{product, q.synced}, delay)
+}
+
+func (q *snapshotQueue) Check(product string) {
+	q.request(snapshotRequest{product, q.checked}, 0)
+}
+
+func (q *snapshotQueue) request(req snapshotRequest, delay time.Duration) {
+	send := func() {
+		select {
+		case q.requests <- req:
+		case <-q.quit:
+		}
+	}
+
+	if delay > 0 {
+		time.AfterFunc(delay, send)
+		return
+	}
+
+	send()
+}
+
+func (q *snapshotQueue) run() {
+	var last time.Time
+
+	for {
+		var req snapshotRequest
+
+		select {
+		case req = <-q.requests:
+		case <-q.quit:
+			return
+		}
+
+		if wait := q.interval - time.Since(last); wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-timer.C:
+			case <-q.quit:
+				timer.Stop()
+				return
+			}
+		}
+
+		snap, err := q.feed.Snapshot(req.product)
+		last = time.Now()
+
+		select {
+		case req.results <- snapshotResult{req.product, snap, err}:
+		case <-q.quit:
+			return
+		}
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"git.cotugno.family/kevin/spectator/exhibit"
+	"git.cotugno.family/kevin/spectator/simulator"
 	"github.com/shopspring/decimal"
 
+	"context"
+	"errors"
+	"flag"
 	"image"
 	"log"
+	"os"
 	"sync"
 	"time"
 	"unicode/utf8"
@@ -14,10 +19,19 @@ import (
 const (
 	coin       = "ETH-USD"
 	timeFormat = "15:04:05"
+	staleTitle = "STALE"
+
+	simulatorDepth    = 50
+	simulatorInterval = 50 * time.Millisecond
 )
 
 var trades = NewQueue()
 
+type trade struct {
+	Message
+	own bool
+}
+
 var terminal *exhibit.Terminal
 var ob *OrderBook
 
@@ -26,6 +40,7 @@ var topAsks *exhibit.ListWidget
 var topBids *exhibit.ListWidget
 var midPrice *exhibit.ListWidget
 var history *exhibit.ListWidget
+var statsPanel *exhibit.ListWidget
 
 var numLock sync.Mutex
 var num int
@@ -33,13 +48,63 @@ var num int
 var sizeLock sync.Mutex
 var sizeChanged bool
 
-var low, high decimal.Decimal
+var sandbox = flag.Bool("sandbox", false, "use the Coinbase sandbox endpoints")
+var wsURL = flag.String("ws", "", "websocket feed URL")
+var restURL = flag.String("rest", "", "REST API base URL")
+var level2 = flag.Bool("level2", false, "maintain an aggregated level two book")
+var record = flag.String("record", "", "record the raw feed to a capture file")
+var replay = flag.String("replay", "", "replay the feed from a capture file")
+var speed = flag.Float64("speed", 1, "replay speed multiplier, 0 for no pacing")
+var historyWindow = flag.Duration("history", 0, "keep this much book history for paused playback")
+var simulate = flag.Bool("simulate", false, "run against a local exchange simulator")
+var validate = flag.Duration("validate", 0, "validate the book against a REST snapshot at this interval")
+var metricsAddr = flag.String("metrics", "", "serve Prometheus metrics on this address")
+var apiAddr = flag.String("http", "", "serve the book, trades and stats as JSON on this address")
+var headless = flag.Bool("headless", false, "run without the terminal UI")
+var paperTrading = flag.Bool("paper", false, "enable paper trading keys")
+var paperOrderSize = flag.String("paper-size", "0.01", "paper trading order size")
 
 func main() {
-	var err error
+	flag.Parse()
+
+	opts, err := options()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.Recorder != nil {
+		defer opts.Recorder.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if *simulate {
+		sim, err := startSimulator(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer sim.Close()
+
+		opts.WebsocketURL = sim.WebsocketURL()
+		opts.RestURL = sim.RestURL()
+	}
+
+	if *headless {
+		err = runHeadless(ctx, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	terminal, err = exhibit.Init(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer terminal.Close()
 
-	terminal = exhibit.Init()
-	defer terminal.Shutdown()
 	terminal.HideCursor()
 
 	window = &exhibit.WindowWidget{}
@@ -58,15 +123,43 @@ func main() {
 	window.AddWidget(topBids)
 	window.AddWidget(history)
 
-	scene := exhibit.Scene{terminal, window}
+	statsPanel = newStatsPanel()
+	window.AddWidget(statsPanel)
+
+	scene := exhibit.Scene{Terminal: terminal, Window: window}
 
 	watchSize(terminal)
 
-	ob, err = NewOrderBook(coin)
+	ob, err = NewOrderBook(ctx, coin, opts)
 	if err != nil {
+		terminal.Close()
 		log.Fatal(err)
 	}
 
+	if *metricsAddr != "" {
+		err = serveMetrics(*metricsAddr)
+		if err != nil {
+			terminal.Close()
+			log.Fatal(err)
+		}
+	}
+
+	if *apiAddr != "" {
+		err = serveAPI(*apiAddr)
+		if err != nil {
+			terminal.Close()
+			log.Fatal(err)
+		}
+	}
+
+	if *paperTrading {
+		err = startPaper(*paperOrderSize)
+		if err != nil {
+			terminal.Close()
+			log.Fatal(err)
+		}
+	}
+
 	go func() {
 	Loop:
 		for e := range terminal.Event {
@@ -74,24 +167,130 @@ func main() {
 			case exhibit.Eventq:
 				fallthrough
 			case exhibit.EventCtrC:
-				ob.Shutdown()
+				cancel()
 				break Loop
+			case exhibit.Eventp:
+				togglePause()
+			case exhibit.Eventn:
+				stepForward()
+			case exhibit.EventLBracket:
+				jump(-jumpInterval)
+			case exhibit.EventRBracket:
+				jump(jumpInterval)
+			case exhibit.Eventb:
+				paperJoin(Buy)
+			case exhibit.Events:
+				paperJoin(Sell)
+			case exhibit.EventB:
+				paperMarket(Buy)
+			case exhibit.EventS:
+				paperMarket(Sell)
+			case exhibit.Eventc:
+				paperCancel()
 			}
 		}
 	}()
 
+	watchState(ob)
+	watchStats(ob)
+
 	go renderLoop(&scene, 100*time.Millisecond)
 
-	updateOrders("sell")
-	updateOrders("buy")
+	refreshOrders()
 
-	for msg := range ob.Msg {
-		updateOrders(msg.Side)
+	sub := ob.Subscribe(DefaultSubscriptionBuffer, PolicyBlock)
 
-		if msg.Type == "match" {
+	for msg := range sub.C {
+		if msg.Type == TypeMatch {
 			addTrade(msg)
 		}
+
+		if isPaused() {
+			continue
+		}
+
+		refreshOrders()
+
+		if msg.Type == TypeMatch {
+			renderTrades()
+			showPaper()
+		}
+	}
+}
+
+func options() (Options, error) {
+	var opts Options
+	var err error
+
+	if *sandbox {
+		opts.WebsocketURL = SandboxWebsocketURL
+		opts.RestURL = SandboxRestURL
+	}
+
+	if *level2 {
+		opts.Mode = ModeLevel2
+	}
+
+	if *simulate && *level2 {
+		return opts, errors.New("The simulator only serves the full channel")
+	}
+
+	if *wsURL != "" {
+		opts.WebsocketURL = *wsURL
+	}
+
+	if *restURL != "" {
+		opts.RestURL = *restURL
+	}
+
+	if *historyWindow > 0 {
+		opts.CheckpointInterval = *historyWindow / DefaultCheckpointLimit
+		if opts.CheckpointInterval < checkpointInterval {
+			opts.CheckpointInterval = checkpointInterval
+		}
+
+		opts.Checkpoints = NewMemoryCheckpointStore(*historyWindow, DefaultCheckpointLimit)
+		opts.MessageLog = NewMemoryMessageLog(*historyWindow, DefaultMessageLogLimit)
+	}
+
+	opts.ValidateInterval = *validate
+	opts.ResyncInvalid = true
+
+	opts.Credentials = Credentials{
+		Key:        os.Getenv("CB_ACCESS_KEY"),
+		Secret:     os.Getenv("CB_ACCESS_SECRET"),
+		Passphrase: os.Getenv("CB_ACCESS_PASSPHRASE"),
+	}
+
+	if *replay != "" {
+		opts.Feed, err = NewReplayFeed(*replay, *speed)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if *record != "" {
+		opts.Recorder, err = NewRecorder(*record)
+		if err != nil {
+			return opts, err
+		}
 	}
+
+	return opts, nil
+}
+
+func startSimulator(ctx context.Context) (*simulator.Simulator, error) {
+	sim := simulator.New(simulator.Config{Seed: time.Now().UnixNano()})
+	sim.Populate(simulatorDepth)
+
+	err := sim.Listen("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	go sim.Run(ctx, simulatorInterval)
+
+	return sim, nil
 }
 
 func numPerSide() int {
@@ -107,17 +306,6 @@ func numOfOrderPerSide(y int) int {
 	return (total / 2)
 }
 
-func flatten(entries Entries) (decimal.Decimal, decimal.Decimal) {
-	var price, size decimal.Decimal
-
-	for _, v := range entries {
-		price = v.Price
-		size = size.Add(v.Size)
-	}
-
-	return price, size
-}
-
 func renderLoop(scene *exhibit.Scene, interval time.Duration) {
 	timer := time.NewTicker(interval)
 	changed := time.NewTicker(2 * time.Second)
@@ -130,7 +318,9 @@ func renderLoop(scene *exhibit.Scene, interval time.Duration) {
 				setSizeChanged(false)
 			}
 		case <-timer.C:
+			start := time.Now()
 			scene.Render()
+			renderTimes.Observe(time.Since(start))
 		}
 	}
 }
@@ -153,6 +343,11 @@ func recalcSizes(sz image.Point) {
 		history.SetOrigin(hOr)
 	}
 
+	sSize := statsSize(hOr.X)
+	if statsPanel.Size() != sSize {
+		statsPanel.SetSize(sSize)
+	}
+
 	num := numOfOrderPerSide(sz.Y)
 	size := image.Point{23, num}
 	if topAsks.Size() != size {
@@ -201,9 +396,9 @@ func fmtObEntry(price, size decimal.Decimal) string {
 func fmtHistoryEntry(msg Message) string {
 	var arrow string
 	switch msg.Side {
-	case "buy":
+	case Buy:
 		arrow = "↓"
-	case "sell":
+	case Sell:
 		arrow = "↑"
 	}
 
@@ -217,11 +412,10 @@ func fmtHistoryEntry(msg Message) string {
 	return s
 }
 
-func fmtMid(high, low decimal.Decimal) string {
-	diff := low.Sub(high)
-	mid := high.Add(diff.Div(decimal.New(2, 0))).StringFixed(3)
+func fmtMid(snap BookSnapshot) string {
+	mid := snap.Mid().StringFixed(3)
 
-	return padString(mid, 9) + padString(diff.StringFixed(2), 14)
+	return padString(mid, 9) + padString(snap.Spread().StringFixed(2), 14)
 }
 
 func watchSize(t *exhibit.Terminal) {
@@ -233,95 +427,99 @@ func watchSize(t *exhibit.Terminal) {
 	}()
 }
 
-func updateOrders(side string) {
-	n := numPerSide()
-	entries := ob.Entries(side, n)
+func watchState(o *OrderBook) {
+	go func() {
+		for s := range o.State {
+			border := window.Border()
+
+			switch s {
+			case StateLive:
+				border.ForegroundColor = exhibit.FGYellow
+				if border.Title == staleTitle {
+					border.Title = ""
+				}
+			case StateConnecting, StateResyncing:
+				border.ForegroundColor = exhibit.FGCyan
+			case StateDown:
+				border.ForegroundColor = exhibit.FGRed
+			case StateStale:
+				border.ForegroundColor = exhibit.FGMagenta
+				border.Title = staleTitle
+			}
 
-	switch side {
-	case "sell":
-		updateAsks(entries)
-	case "buy":
-		updateBids(entries)
-	}
+			window.SetBorder(border)
+		}
+	}()
+}
+
+func refreshOrders() {
+	snap := viewBook().Snapshot(numPerSide())
+
+	updateAsks(snap.Asks)
+	updateBids(snap.Bids)
 
-	midPrice.AddEntry(ListEntry{Value: fmtMid(high, low)})
+	midPrice.AddEntry(ListEntry{Value: fmtMid(snap)})
 	midPrice.Commit()
 }
 
-func updateAsks(entries []Entries) {
-	for i := len(entries) - 1; i >= 0; i-- {
-		entry := entries[i]
-		price, size := flatten(entry)
+func updateAsks(levels []Level) {
+	for i := len(levels) - 1; i >= 0; i-- {
+		level := levels[i]
 
-		topAsks.AddEntry(ListEntry{Value: fmtObEntry(price, size),
-			Attrs: exhibit.Attributes{ForegroundColor: exhibit.FGRed}})
-
-		if i == 0 {
-			low = price
-		}
+		topAsks.AddEntry(ListEntry{Value: fmtObEntry(level.Price, level.Size),
+			Attrs: levelAttrs(level, exhibit.FGRed)})
 	}
 
 	topAsks.Commit()
 }
 
-func updateBids(entries []Entries) {
-	for i := 0; i < len(entries); i++ {
-		entry := entries[i]
-		price, size := flatten(entry)
-
-		topBids.AddEntry(ListEntry{Value: fmtObEntry(price, size),
-			Attrs: exhibit.Attributes{ForegroundColor: exhibit.FGGreen}})
+func updateBids(levels []Level) {
+	for i := 0; i < len(levels); i++ {
+		level := levels[i]
 
-		if i == 0 {
-			high = price
-		}
+		topBids.AddEntry(ListEntry{Value: fmtObEntry(level.Price, level.Size),
+			Attrs: levelAttrs(level, exhibit.FGGreen)})
 	}
 
 	topBids.Commit()
 }
 
-func addTrade(msg Message) {
-	if trades.Length() == 256 {
-		trades.Dequeue()
-	}
-
-	trades.Enqueue(msg)
+func levelAttrs(level Level, fg exhibit.ForegroundColor) exhibit.Attributes {
+	attrs := exhibit.Attributes{ForegroundColor: fg}
 
-	max := history.Size().Y
-	length := trades.Length()
-	var num int
-	if length > max {
-		num = max
-	} else {
-		num = length
+	if level.Own.IsPositive() {
+		attrs.ForegroundColor = exhibit.FGWhite
+		attrs.BackgroundColor = exhibit.BGBlue
 	}
 
-	for i := 0; i < num; i++ {
-		var index int
-
-		adj := trades.Length() - i - 1
+	return attrs
+}
 
-		if adj < 0 {
-			break
-		} else {
-			index = adj
-		}
+func addTrade(msg Message) {
+	trades.Push(trade{msg, ob.IsOwn(msg.MakerOrderId) || ob.IsOwn(msg.TakerOrderId)})
+}
 
-		e := trades.Element(index)
+func renderTrades() {
+	max := history.Size().Y
 
-		if e != nil {
-			msg := e.(Message)
+	if max > 0 {
+		for _, e := range trades.Last(max) {
+			t := e.(trade)
 
 			var attrs exhibit.Attributes
 
-			switch msg.Side {
-			case "buy":
+			switch t.Side {
+			case Buy:
 				attrs.ForegroundColor = exhibit.FGRed
-			case "sell":
+			case Sell:
 				attrs.ForegroundColor = exhibit.FGGreen
 			}
 
-			le := ListEntry{fmtHistoryEntry(msg), attrs}
+			if t.own {
+				attrs.BackgroundColor = exhibit.BGBlue
+			}
+
+			le := ListEntry{fmtHistoryEntry(t.Message), attrs}
 			history.AddEntry(le)
 		}
 	}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const rateWindow = 10
+
+type Stats struct {
+	Messages     int64
+	ByType       map[MessageType]int64
+	Rate         float64
+	Gaps         int64
+	Syncs        int64
+	Resyncs      int64
+	Reconnects   int64
+	Errors       int64
+	FeedLatency  HistogramSummary
+	ApplyLatency HistogramSummary
+	TotalLatency HistogramSummary
+}
+
+type bookStats struct {
+	lock     sync.Mutex
+	messages int64
+	byType   map[MessageType]int64
+	gaps     int64
+	syncs    int64
+	resyncs  int64
+	seconds  [rateWindow]int64
+	counts   [rateWindow]int64
+
+	feed  Histogram
+	apply Histogram
+	total Histogram
+}
+
+func (o *OrderBook) Stats() Stats {
+	st := o.stats.snapshot(time.Now())
+
+	if o.manager != nil {
+		st.Reconnects, st.Errors = o.manager.counts()
+	}
+
+	return st
+}
+
+func (s *bookStats) observe(msg Message, applied time.Time) {
+	s.lock.Lock()
+	s.messages++
+
+	if s.byType == nil {
+		s.byType = make(map[MessageType]int64)
+	}
+	s.byType[msg.Type]++
+
+	sec := applied.Unix()
+	i := sec % rateWindow
+	if s.seconds[i] != sec {
+		s.seconds[i] = sec
+		s.counts[i] = 0
+	}
+	s.counts[i]++
+	s.lock.Unlock()
+
+	if msg.Received.IsZero() {
+		return
+	}
+
+	s.apply.Observe(applied.Sub(msg.Received))
+
+	if msg.Time.IsZero() {
+		return
+	}
+
+	s.feed.Observe(msg.Received.Sub(msg.Time))
+	s.total.Observe(applied.Sub(msg.Time))
+}
+
+func (s *bookStats) gap() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.gaps++
+}
+
+func (s *bookStats) sync() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.syncs++
+}
+
+func (s *bookStats) resync() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.resyncs++
+}
+
+func (s *bookStats) snapshot(now time.Time) Stats {
+	var st Stats
+
+	s.lock.Lock()
+	st.Messages = s.messages
+	st.Gaps = s.gaps
+	st.Syncs = s.syncs
+	st.Resyncs = s.resyncs
+
+	st.ByType = make(map[MessageType]int64, len(s.byType))
+	for t, n := range s.byType {
+		st.ByType[t] = n
+	}
+
+	var count int64
+	for i, sec := range s.seconds {
+		if sec > now.Unix()-rateWindow && sec <= now.Unix() {
+			count += s.counts[i]
+		}
+	}
+	s.lock.Unlock()
+
+	st.Rate = float64(count) / rateWindow
+	st.FeedLatency = s.feed.Summary()
+	st.ApplyLatency = s.apply.Summary()
+	st.TotalLatency = s.total.Summary()
+
+	return st
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"git.cotugno.family/kevin/spectator/exhibit"
+
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	statsInterval = time.Second
+	statsWidth    = 30
+	statsMinWidth = 22
+)
+
+var renderTimes Histogram
+
+var statsOrigin = image.Pt(25, 0)
+
+func newStatsPanel() *exhibit.ListWidget {
+	panel := &exhibit.ListWidget{}
+	panel.SetOrigin(statsOrigin)
+	panel.SetSize(image.Pt(statsWidth, 5))
+
+	return panel
+}
+
+func statsSize(right int) image.Point {
+	free := right - 1 - statsOrigin.X
+
+	switch {
+	case free >= statsWidth:
+		return image.Pt(statsWidth, 5)
+	case free >= statsMinWidth:
+		return image.Pt(free, 5)
+	default:
+		return image.Point{}
+	}
+}
+
+func watchStats(o *OrderBook) {
+	go func() {
+		ticker := time.NewTicker(statsInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			renderStats(o.Stats(), renderTimes.Summary())
+		}
+	}()
+}
+
+func renderStats(st Stats, render HistogramSummary) {
+	attrs := exhibit.Attributes{ForegroundColor: exhibit.FGWhite}
+
+	lines := []string{
+		fmt.Sprintf("msg/s %8.1f", st.Rate),
+		fmtLatency("net", st.FeedLatency),
+		fmtLatency("book", st.ApplyLatency),
+		fmtLatency("draw", render),
+		fmt.Sprintf("gaps %v resyncs %v", st.Gaps, st.Resyncs),
+	}
+
+	for _, l := range lines {
+		statsPanel.AddEntry(ListEntry{Value: l, Attrs: attrs})
+	}
+
+	statsPanel.Commit()
+}
+
+func fmtLatency(name string, s HistogramSummary) string {
+	return padString(name, 4) + " " + padString(fmtDuration(s.P50), 8) +
+		" " + padString(fmtDuration(s.P99), 8)
+}
+
+func fmtDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	case d >= time.Millisecond:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%dus", d/time.Microsecond)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestStatsSize(t *testing.T) {
+	tests := []struct {
+		columns int
+		want    image.Point
+	}{
+		{80, image.Point{}},
+		{82, image.Point{}},
+		{83, image.Pt(22, 5)},
+		{90, image.Pt(29, 5)},
+		{120, image.Pt(30, 5)},
+	}
+
+	for _, tt := range tests {
+		right := tt.columns - 35
+
+		got := statsSize(right)
+		if got != tt.want {
+			t.Errorf("statsSize(%v) = %v, expected %v", right, got, tt.want)
+		}
+
+		if got.X > 0 && statsOrigin.X+got.X >= right {
+			t.Errorf("Stats panel overlaps history at %v columns", tt.columns)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type SlowPolicy int
+
+const (
+	PolicyBlock SlowPolicy = iota
+	PolicyDropOldest
+	PolicyDisconnect
+)
+
+const DefaultSubscriptionBuffer = 2048
+
+type Subscription struct {
+	C <-chan Message
+
+	c      chan Message
+	policy SlowPolicy
+
+	dropped uint64
+
+	done     chan struct{}
+	doneOnce sync.Once
+
+	book *OrderBook
+}
+
+func (o *OrderBook) Subscribe(buffer int, policy SlowPolicy) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+
+	s := &Subscription{policy: policy, book: o}
+	s.c = make(chan Message, buffer)
+	s.C = s.c
+	s.done = make(chan struct{})
+
+	o.subsLock.Lock()
+	defer o.subsLock.Unlock()
+
+	if o.subsClosed {
+		s.stop()
+		close(s.c)
+		return s
+	}
+
+	o.subs[s] = struct{}{}
+
+	return s
+}
+
+func (o *OrderBook) Unsubscribe(s *Subscription) {
+	s.stop()
+
+	o.subsLock.Lock()
+	defer o.subsLock.Unlock()
+
+	if _, ok := o.subs[s]; !ok {
+		return
+	}
+
+	delete(o.subs, s)
+	close(s.c)
+}
+
+func (o *OrderBook) Subscribers() int {
+	o.subsLock.RLock()
+	defer o.subsLock.RUnlock()
+
+	return len(o.subs)
+}
+
+func (s *Subscription) Close() {
+	s.book.Unsubscribe(s)
+}
+
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+func (s *Subscription) Policy() SlowPolicy {
+	return s.policy
+}
+
+func (s *Subscription) stop() {
+	s.doneOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *Subscription) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Subscription) deliver(msg Message) bool {
+	if s.stopped() {
+		return true
+	}
+
+	switch s.policy {
+	case PolicyDropOldest:
+		for {
+			select {
+			case s.c <- msg:
+				return true
+			default:
+			}
+
+			select {
+			case <-s.c:
+				atomic.AddUint64(&s.dropped, 1)
+			default:
+			}
+		}
+	case PolicyDisconnect:
+		select {
+		case s.c <- msg:
+			return true
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			s.stop()
+			return false
+		}
+	default:
+		select {
+		case s.c <- msg:
+		case <-s.done:
+		}
+
+		return true
+	}
+}
+
+func (o *OrderBook) publish(msg Message) {
+	var slow []*Subscription
+
+	o.subsLock.RLock()
+	for s := range o.subs {
+		if !s.deliver(msg) {
+			slow = append(slow, s)
+		}
+	}
+	o.subsLock.RUnlock()
+
+	for _, s := range slow {
+		o.Unsubscribe(s)
+	}
+}
+
+func (o *OrderBook) closeSubscriptions() {
+	o.subsLock.Lock()
+	defer o.subsLock.Unlock()
+
+	for s := range o.subs {
+		s.stop()
+		close(s.c)
+	}
+
+	o.subs = make(map[*Subscription]struct{})
+	o.subsClosed = true
+}
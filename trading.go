@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+)
+
+var paper *PaperTrader
+var paperSize decimal.Decimal
+
+func startPaper(size string) error {
+	var err error
+
+	paperSize, err = decimal.NewFromString(size)
+	if err != nil {
+		return err
+	}
+
+	if !paperSize.IsPositive() {
+		return fmt.Errorf("Invalid paper order size: %v", size)
+	}
+
+	paper = NewPaperTrader(ob, DefaultMakerFee, DefaultTakerFee)
+
+	return nil
+}
+
+func paperJoin(side Side) {
+	if paper == nil {
+		return
+	}
+
+	snap := ob.Snapshot(1)
+
+	best, ok := snap.BestBid()
+	if side == Sell {
+		best, ok = snap.BestAsk()
+	}
+
+	if !ok {
+		return
+	}
+
+	_, err := paper.Limit(side, best.Price, paperSize)
+	if err != nil {
+		setTitle(err.Error())
+		return
+	}
+
+	showPaper()
+}
+
+func paperMarket(side Side) {
+	if paper == nil {
+		return
+	}
+
+	_, err := paper.Market(side, paperSize)
+	if err != nil {
+		setTitle(err.Error())
+		return
+	}
+
+	showPaper()
+}
+
+func paperCancel() {
+	if paper == nil {
+		return
+	}
+
+	paper.CancelAll()
+	showPaper()
+}
+
+func showPaper() {
+	if paper == nil || isPaused() {
+		return
+	}
+
+	pos := paper.Position()
+
+	setTitle(fmt.Sprintf("PAPER %v @ %v PnL %v fees %v open %v",
+		pos.Size.String(), pos.AvgPrice.StringFixed(2), pos.PnL.StringFixed(2),
+		pos.Fees.StringFixed(2), len(paper.Orders())))
+}
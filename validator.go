@@ -0,0 +1,197 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"fmt"
+)
+
+type Discrepancy struct {
+	Side     Side
+	Price    decimal.Decimal
+	Expected decimal.Decimal
+	Actual   decimal.Decimal
+}
+
+type ValidationError struct {
+	Product       string
+	Sequence      int64
+	Discrepancies []Discrepancy
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Book %v diverged from snapshot at sequence %v: %v levels differ",
+		e.Product, e.Sequence, len(e.Discrepancies))
+}
+
+type IntegrityError struct {
+	Product  string
+	Sequence int64
+	Reason   string
+}
+
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("Book %v failed integrity check at sequence %v: %v",
+		e.Product, e.Sequence, e.Reason)
+}
+
+func (o *OrderBook) Validate(snap FeedSnapshot) []Discrepancy {
+	o.bookLock.RLock()
+	defer o.bookLock.RUnlock()
+
+	var diffs []Discrepancy
+
+	diffs = append(diffs, o.compareSide(Buy, snap.Bids)...)
+	diffs = append(diffs, o.compareSide(Sell, snap.Asks)...)
+
+	return diffs
+}
+
+func (o *OrderBook) compareSide(side Side, entries []Entry) []Discrepancy {
+	var diffs []Discrepancy
+
+	expected := make(map[int64]Level)
+	for _, e := range entries {
+		t := o.tick(e.Price)
+
+		l := expected[t]
+		l.Price = e.Price
+		l.Size = l.Size.Add(e.Size)
+		expected[t] = l
+	}
+
+	it := o.index(side).Iterator()
+	for it.Next() {
+		l, t := it.Value(), it.Tick()
+
+		want, ok := expected[t]
+		delete(expected, t)
+
+		if ok && want.Size.Equal(l.size) {
+			continue
+		}
+
+		diffs = append(diffs, Discrepancy{side, l.price, want.Size, l.size})
+	}
+
+	for _, want := range expected {
+		diffs = append(diffs, Discrepancy{side, want.Price, want.Size, decimal.Zero})
+	}
+
+	return diffs
+}
+
+func (o *OrderBook) validate(snap FeedSnapshot, snaps *snapshotQueue) {
+	seq := o.Sequence()
+
+	if o.syncing {
+		o.check = nil
+		return
+	}
+
+	if seq < snap.Sequence {
+		o.check = &snap
+		return
+	}
+
+	o.check = nil
+
+	b := o
+	if seq > snap.Sequence {
+		rolled, ok := o.rollForward(snap)
+		if ok {
+			snap = rolled
+		} else {
+			var err error
+
+			b, err = o.Reconstructor().AtSequence(o.coin, snap.Sequence)
+			if err == nil && b.Sequence() != snap.Sequence {
+				err = errNoCheckpoint
+			}
+			if err != nil {
+				o.sendError(fmt.Errorf("Unable to validate %v at sequence %v: %v",
+					o.coin, snap.Sequence, err))
+				return
+			}
+		}
+	}
+
+	diffs := b.Validate(snap)
+	if len(diffs) == 0 {
+		return
+	}
+
+	o.sendError(&ValidationError{o.coin, snap.Sequence, diffs})
+
+	if o.manager.resyncInvalid {
+		o.resync(snaps)
+	}
+}
+
+func (o *OrderBook) requestCheck(snaps *snapshotQueue) {
+	if o.syncing || o.validating || o.check != nil {
+		return
+	}
+
+	o.validating = true
+	o.checkLog = o.checkLog[:0]
+
+	snaps.Check(o.coin)
+}
+
+func (o *OrderBook) rollForward(snap FeedSnapshot) (FeedSnapshot, bool) {
+	log := o.checkLog
+	o.checkLog = o.checkLog[:0]
+
+	seq := o.Sequence()
+	if len(log) == 0 || log[0].Sequence > snap.Sequence+1 {
+		return FeedSnapshot{}, false
+	}
+
+	b := newOfflineBook(o.coin)
+	b.loadOrderBook(snap)
+
+	for _, msg := range log {
+		if !b.apply(msg) {
+			return FeedSnapshot{}, false
+		}
+	}
+
+	return b.Checkpoint().Snapshot(), b.Sequence() == seq
+}
+
+func (o *OrderBook) checkIntegrity(msg Message, tick int64) error {
+	for _, id := range []string{msg.OrderId, msg.MakerOrderId} {
+		l, ok := o.orders[id]
+		if !ok {
+			continue
+		}
+
+		e, _ := l.Get(id)
+		if e.Size.IsNegative() {
+			return &IntegrityError{o.coin, msg.Sequence,
+				fmt.Sprintf("negative size %v for order %v", e.Size, id)}
+		}
+	}
+
+	if l, ok := o.level(msg.Side, tick); ok && l.size.IsNegative() {
+		return &IntegrityError{o.coin, msg.Sequence,
+			fmt.Sprintf("negative size %v at %v %v", l.size, msg.Side, msg.Price)}
+	}
+
+	bid, okBid := o.best(Buy)
+	ask, okAsk := o.best(Sell)
+
+	crossed := okBid && okAsk && !bid.LessThan(ask)
+	if crossed == o.crossed {
+		return nil
+	}
+
+	o.crossed = crossed
+	if !crossed {
+		return nil
+	}
+
+	return &IntegrityError{o.coin, msg.Sequence,
+		fmt.Sprintf("crossed book, bid %v ask %v", bid, ask)}
+}
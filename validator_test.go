@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+
+	"strings"
+	"testing"
+)
+
+func validatorBook() (*OrderBook, chan error) {
+	m := &BookManager{err: make(chan error, 8)}
+
+	o := newOrderBook("ETH-USD", m)
+	o.loadOrderBook(FeedSnapshot{Sequence: 10, Bids: []Entry{
+		{Id: "a", Side: Buy, Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(3)},
+	}})
+
+	o.validating = true
+
+	msgs := []Message{
+		{Type: TypeOpen, Sequence: 11, Side: Sell, OrderId: "b",
+			Price: decimal.NewFromInt(101), RemainingSize: decimal.NewFromInt(2)},
+		{Type: TypeOpen, Sequence: 12, Side: Buy, OrderId: "c",
+			Price: decimal.NewFromInt(98), RemainingSize: decimal.NewFromInt(4)},
+		{Type: TypeMatch, Sequence: 13, Side: Buy, MakerOrderId: "a",
+			TakerOrderId: "taker", Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(1)},
+	}
+
+	for _, msg := range append(msgs, msgs[2]) {
+		o.handle(msg, nil)
+	}
+
+	o.validating = false
+
+	return o, m.err
+}
+
+func validatorSnapshot(extra ...Entry) FeedSnapshot {
+	return FeedSnapshot{Sequence: 11,
+		Bids: append([]Entry{
+			{Id: "a", Side: Buy, Price: decimal.NewFromInt(99), Size: decimal.NewFromInt(3)},
+		}, extra...),
+		Asks: []Entry{
+			{Id: "b", Side: Sell, Price: decimal.NewFromInt(101), Size: decimal.NewFromInt(2)},
+		},
+	}
+}
+
+func TestValidateRollsSnapshotForward(t *testing.T) {
+	o, errs := validatorBook()
+	o.validate(validatorSnapshot(), nil)
+
+	select {
+	case err := <-errs:
+		t.Fatalf("Unexpected error: %v", err)
+	default:
+	}
+
+	o, errs = validatorBook()
+	o.validate(validatorSnapshot(Entry{Id: "x", Side: Buy, Price: decimal.NewFromInt(97),
+		Size: decimal.NewFromInt(1)}), nil)
+
+	select {
+	case err := <-errs:
+		verr, ok := err.(*ValidationError)
+		if !ok || len(verr.Discrepancies) != 1 {
+			t.Fatalf("Expected one discrepancy, got %v", err)
+		}
+	default:
+		t.Fatal("Expected a validation error")
+	}
+}
+
+func TestValidateReportsMissingHistory(t *testing.T) {
+	o, errs := validatorBook()
+	o.checkLog = o.checkLog[:0]
+
+	o.validate(validatorSnapshot(), nil)
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "Unable to validate") {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	default:
+		t.Fatal("Expected an error when the snapshot cannot be compared")
+	}
+}